@@ -0,0 +1,168 @@
+package gosoap
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// wsdlCache is a directory of documents fetched over http(s). Cached documents are revalidated with
+// conditional requests and used as a fallback if the server can't be reached or fails.
+type wsdlCache string
+
+// cachedDocument is the metadata stored next to the content of a document
+type cachedDocument struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+
+	content []byte
+}
+
+// fetch loads a document from the server, revalidating the cached copy if there is one
+func (cache wsdlCache) fetch(ctx context.Context, u string, c *http.Client) ([]byte, error) {
+	cached, err := cache.load(u)
+	if err != nil {
+		// an unreadable cache must not break loading, fetch the document as if it wasn't cached
+		cached = nil
+	}
+	header := http.Header{}
+	if cached != nil {
+		if cached.ETag != "" {
+			header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	res, err := httpGet(ctx, u, c, header)
+	if err != nil {
+		if cached != nil && ctx.Err() == nil {
+			// the server is unreachable, use the last good copy
+			return cached.content, nil
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && cached != nil:
+		return cached.content, nil
+	case res.StatusCode >= http.StatusInternalServerError && cached != nil:
+		return cached.content, nil
+	}
+	if err := checkStatus(res); err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if !isDescription(content) {
+		// error pages of proxies and portals are often served with a 2xx status, they are never cached
+		if cached != nil {
+			return cached.content, nil
+		}
+		return content, nil
+	}
+	doc := &cachedDocument{
+		URL:          u,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		content:      content,
+	}
+	// the cache is only an optimization, the fetched document is used even if it can't be stored
+	_ = cache.store(doc)
+	return content, nil
+}
+
+// isDescription reports whether content is an XML document with a WSDL 1.1, WSDL 2.0 or XML Schema root element
+func isDescription(content []byte) bool {
+	d := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			switch start.Name.Space {
+			case wsdlNS, wsdl2NS, xsdNS:
+				return true
+			}
+			return false
+		}
+	}
+}
+
+// path returns the base path of the files of a cached document
+func (cache wsdlCache) path(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return filepath.Join(string(cache), hex.EncodeToString(sum[:]))
+}
+
+// load returns the cached copy of a document or nil if there is none
+func (cache wsdlCache) load(u string) (*cachedDocument, error) {
+	p := cache.path(u)
+	meta, err := os.ReadFile(p + ".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc cachedDocument
+	if err := json.Unmarshal(meta, &doc); err != nil || doc.URL != u {
+		// treat corrupt entries as missing, they are replaced by the next successful fetch
+		return nil, nil
+	}
+	doc.content, err = os.ReadFile(p + ".xml")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// store writes a document to the cache, files are replaced atomically so concurrent readers never see partial content
+func (cache wsdlCache) store(doc *cachedDocument) error {
+	if err := os.MkdirAll(string(cache), 0o755); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	p := cache.path(doc.URL)
+	if err := writeFileAtomic(p+".xml", doc.content); err != nil {
+		return err
+	}
+	return writeFileAtomic(p+".json", meta)
+}
+
+func writeFileAtomic(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
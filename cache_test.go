@@ -0,0 +1,114 @@
+package gosoap
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSDLCache(t *testing.T) {
+	t.Parallel()
+	quote, err := os.ReadFile("testdata/quote.wsdl")
+	require.NoError(t, err)
+
+	var status atomic.Int32
+	var fetched, revalidated atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code := int(status.Load()); code != 0 {
+			w.WriteHeader(code)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fetched.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(quote)
+	}))
+	t.Cleanup(server.Close)
+	config := func() *Config {
+		return &Config{Client: server.Client(), WSDLCacheDir: t.TempDir() + "/wsdl"}
+	}
+	cached := config()
+	load := func(ctx context.Context, config *Config) error {
+		_, err := NewClientWithContext(ctx, SourceFromURI(server.URL+"/quote.wsdl"), config)
+		return err
+	}
+
+	require.NoError(t, load(context.Background(), cached))
+	assert.EqualValues(t, 1, fetched.Load())
+	require.NoError(t, load(context.Background(), cached))
+	assert.EqualValues(t, 1, fetched.Load(), "cached copy is revalidated")
+	assert.EqualValues(t, 1, revalidated.Load())
+
+	status.Store(http.StatusServiceUnavailable)
+	assert.NoError(t, load(context.Background(), cached), "falls back to the cached copy")
+	assert.EqualError(t, load(context.Background(), config()), `could not load WSDL: could not fetch WSDL resource: unexpected HTTP status "503 Service Unavailable"`)
+
+	status.Store(http.StatusNotFound)
+	assert.EqualError(t, load(context.Background(), cached), `could not load WSDL: could not fetch WSDL resource: unexpected HTTP status "404 Not Found"`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, load(ctx, cached), context.Canceled)
+
+	server.Close()
+	status.Store(0)
+	assert.NoError(t, load(context.Background(), cached), "falls back to the cached copy")
+}
+
+func TestWSDLCacheFailures(t *testing.T) {
+	t.Parallel()
+	quote, err := os.ReadFile("testdata/quote.wsdl")
+	require.NoError(t, err)
+
+	var page atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if page.Load() {
+			_, _ = io.WriteString(w, "<html><body>Please log in</body></html>")
+			return
+		}
+		_, _ = w.Write(quote)
+	}))
+	t.Cleanup(server.Close)
+	u := server.URL + "/quote.wsdl"
+	load := func(dir string) error {
+		_, err := NewClient(SourceFromURI(u), &Config{Client: server.Client(), WSDLCacheDir: dir})
+		return err
+	}
+
+	// a cache directory that can't be written doesn't fail the fetch
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	assert.NoError(t, load(file))
+
+	// an unreadable entry falls back to the network
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(wsdlCache(dir).path(u)+".json", 0o755))
+	assert.NoError(t, load(dir))
+
+	// pages that aren't WSDL are never cached and don't replace the cached copy
+	dir = t.TempDir()
+	page.Store(true)
+	assert.Error(t, load(dir))
+	_, err = os.Stat(wsdlCache(dir).path(u) + ".xml")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	page.Store(false)
+	require.NoError(t, load(dir))
+	page.Store(true)
+	assert.NoError(t, load(dir))
+	content, err := os.ReadFile(wsdlCache(dir).path(u) + ".xml")
+	require.NoError(t, err)
+	assert.Equal(t, quote, content)
+}
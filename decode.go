@@ -0,0 +1,440 @@
+package gosoap
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/SoMuchForSubtlety/gosoap/xsd"
+)
+
+// Node is an element of a response decoded with the schema of the WSDL.
+//
+// Values of simple content and attributes are converted according to their XSD type:
+// integer types to int64 (*big.Int if they don't fit), decimal to *big.Rat, float and double to float64,
+// boolean to bool, dateTime, date and time to time.Time, duration to xsd.Duration, base64Binary and hexBinary to []byte,
+// QName to xml.Name and list types to []any. Other types and values that can't be converted are kept as string.
+type Node struct {
+	Name xml.Name
+	// Type is the XSD type the element was decoded with, it is empty for elements the schema doesn't declare
+	Type xml.Name
+	// Attrs holds the converted attribute values by local name, namespace declarations are omitted.
+	// Attributes the schema doesn't declare which have a namespace are named "{namespace}local", like xml:lang.
+	Attrs map[string]any
+	// Value is the converted content of elements without child elements, nil for nil elements
+	Value any
+	// Text is the character data of the element
+	Text     string
+	Children []*Node
+	// Repeated is set for elements declared with maxOccurs > 1, Get returns their values as a list
+	Repeated bool
+	// Nil is set for elements with xsi:nil="true"
+	Nil bool
+
+	// declared is set for elements the schema declares
+	declared bool
+	// attrText holds the attribute values as they appear in the document
+	attrText map[string]string
+	// content is the text and child nodes of elements with mixed content in document order
+	content []any
+}
+
+// Decode converts the body to a tree of nodes, using the schema of the output message to convert values.
+// The returned node stands for the SOAP body, its children are the elements of the body.
+func (r *Response) Decode() (*Node, error) {
+	if err := r.fault(); err != nil {
+		return nil, err
+	}
+	body, err := prepareBody(r.Body, r.bodyNamespaces, true)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := parseXMLFragment(body, r.bodyNamespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{validator: &validator{set: &xsdSchemaSet{}, patterns: map[string]compiledPattern{}}}
+	var msg *wsdlMessage
+	var rpc bool
+	if r.wsdl != nil && r.wsdl.definitions != nil {
+		d.set = r.wsdl.definitions.schemas
+		msg, _ = r.wsdl.definitions.outputMessage(r.wsdl.binding, r.operation)
+		rpc = r.wsdl.binding.style(r.operation) == "rpc"
+	}
+
+	root := &Node{Name: xml.Name{Local: "Body"}}
+	for _, n := range elementsOf(nodes) {
+		if !rpc {
+			root.Children = append(root.Children, d.node(n, d.set.element(n.Name), false, r.bodyNamespaces))
+			continue
+		}
+		// the rpc wrapper isn't declared in the schema, its accessors are the message parts
+		wrapper := &Node{Name: n.Name, Text: n.text()}
+		ns := append(append(namespaces{}, r.bodyNamespaces...), n.Attr...)
+		for _, accessor := range n.elements() {
+			wrapper.Children = append(wrapper.Children, d.node(accessor, d.partElement(msg, accessor.Name), false, ns))
+		}
+		root.Children = append(root.Children, wrapper)
+	}
+	return root, nil
+}
+
+// fault returns a FaultError if the body holds a SOAP fault
+func (r *Response) fault() error {
+	if len(r.Body) == 0 {
+		return nil
+	}
+	var fault Fault
+	if err := xml.Unmarshal(r.Body, &fault); err == nil && fault.Code != "" {
+		return FaultError{Fault: fault}
+	}
+	return nil
+}
+
+// Find returns the descendants matching a path of element names separated by slashes.
+// A segment matches the local name of an element, "*" matches any element and a 1-based
+// index like Item[2] selects one of several matches, e.g. "GetOrderResponse/Items/Item[2]".
+func (n *Node) Find(path string) []*Node {
+	nodes := []*Node{n}
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		name, index := parseSegment(segment)
+		var next []*Node
+		for _, parent := range nodes {
+			var matches []*Node
+			for _, c := range parent.Children {
+				if name == "*" || c.Name.Local == name {
+					matches = append(matches, c)
+				}
+			}
+			if index > 0 {
+				if index > len(matches) {
+					continue
+				}
+				matches = matches[index-1 : index]
+			}
+			next = append(next, matches...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// Get returns the value at a path like Find, the last segment can be an attribute like "@currency"
+// or "@{http://www.w3.org/XML/1998/namespace}lang", see Attrs.
+// Elements with child elements are returned as *Node, repeated elements without an index as []any.
+// It returns nil if nothing matches.
+func (n *Node) Get(path string) any {
+	path = strings.Trim(path, "/")
+	var attr string
+	if i := strings.LastIndex(path, "@"); i >= 0 {
+		name := path[i+1:]
+		if strings.HasPrefix(name, "{") {
+			_, name, _ = strings.Cut(name, "}")
+		}
+		if !strings.Contains(name, "/") {
+			path, attr = strings.TrimSuffix(path[:i], "/"), path[i+1:]
+		}
+	}
+	nodes := n.Find(path)
+	if attr != "" {
+		if len(nodes) == 0 {
+			return nil
+		}
+		return nodes[0].Attrs[attr]
+	}
+	segments := strings.Split(path, "/")
+	_, index := parseSegment(segments[len(segments)-1])
+	switch {
+	case len(nodes) == 0:
+		return nil
+	case len(nodes) == 1 && (index > 0 || !nodes[0].Repeated):
+		return nodes[0].value()
+	}
+	values := make([]any, 0, len(nodes))
+	for _, c := range nodes {
+		values = append(values, c.value())
+	}
+	return values
+}
+
+func (n *Node) value() any {
+	if len(n.Children) > 0 {
+		return n
+	}
+	return n.Value
+}
+
+// parseSegment splits a path segment like Item[2] into name and index, the index is 0 if there is none
+func parseSegment(segment string) (string, int) {
+	name, rest, ok := strings.Cut(segment, "[")
+	if !ok {
+		return segment, 0
+	}
+	index, err := strconv.Atoi(strings.TrimSuffix(rest, "]"))
+	if err != nil || index < 1 {
+		return name, 0
+	}
+	return name, index
+}
+
+// decoder converts xmlNode trees to nodes, the validator provides the schema lookups
+type decoder struct {
+	*validator
+}
+
+// partElement returns the declaration of an rpc accessor, type parts are declared as local elements
+func (d *decoder) partElement(msg *wsdlMessage, name xml.Name) *xsdElement {
+	if msg == nil {
+		return nil
+	}
+	for _, p := range msg.Parts {
+		switch {
+		case p.Element != "" && p.ElementName.Local == name.Local:
+			return d.set.element(p.ElementName)
+		case p.Element == "" && p.Name == name.Local:
+			return &xsdElement{QName: name, TypeName: p.TypeName}
+		}
+	}
+	return nil
+}
+
+// node decodes an element, decl is nil for elements the schema doesn't declare
+func (d *decoder) node(x *xmlNode, decl *xsdElement, repeated bool, ns namespaces) *Node {
+	ns = append(append(namespaces{}, ns...), x.Attr...)
+	n := &Node{Name: x.Name, Text: x.text(), Repeated: repeated, declared: decl != nil}
+
+	var ct *xsdComplexType
+	var st *xsdSimpleType
+	var builtin xml.Name
+	if decl != nil {
+		decl = d.set.resolveElement(decl)
+		ct, st, builtin = d.set.elementType(decl)
+		n.Type = decl.TypeName
+		if n.Type.Local == "" && isBuiltinType(builtin) {
+			n.Type = builtin
+		}
+	}
+	if typ, ok := x.attr(xsiNS, "type"); ok {
+		if name := ns.resolve(typ); name.Local != "" {
+			if c, s, b := d.set.namedType(name); c != nil || s != nil || isBuiltinType(b) {
+				ct, st, builtin, n.Type = c, s, b, name
+			}
+		}
+	}
+
+	var uses []*xsdAttribute
+	if ct != nil {
+		uses = d.set.attributeUses(ct)
+	}
+	for _, a := range x.Attr {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") || a.Name.Space == xsiNS {
+			continue
+		}
+		if n.Attrs == nil {
+			n.Attrs, n.attrText = map[string]any{}, map[string]string{}
+		}
+		value, declared := d.attribute(a, uses, ns)
+		key := a.Name.Local
+		if !declared && a.Name.Space != "" {
+			key = "{" + a.Name.Space + "}" + a.Name.Local
+		}
+		n.Attrs[key] = value
+		n.attrText[key] = a.Value
+	}
+	if nilled, _ := x.attr(xsiNS, "nil"); nilled == "true" || nilled == "1" {
+		n.Nil = true
+		return n
+	}
+
+	children := x.elements()
+	switch {
+	case len(children) > 0:
+		mixed := strings.TrimSpace(n.Text) != ""
+		for _, c := range x.Children {
+			if c.isText() {
+				if mixed {
+					n.content = append(n.content, c.Text)
+				}
+				continue
+			}
+			var cdecl *xsdElement
+			var rep bool
+			if ct != nil {
+				cdecl, rep = d.childElement(d.set.contentModel(ct), c.Name, false, 0)
+			}
+			child := d.node(c, cdecl, rep, ns)
+			n.Children = append(n.Children, child)
+			if mixed {
+				n.content = append(n.content, child)
+			}
+		}
+	case ct != nil && ct.SimpleContent != nil:
+		st, builtin, _ = d.simpleContent(ct)
+		n.Value = d.convert(n.Text, st, builtin, ns, 0)
+	case ct == nil && (st != nil || isBuiltinType(builtin)):
+		n.Value = d.convert(n.Text, st, builtin, ns, 0)
+	default:
+		n.Value = n.Text
+	}
+	return n
+}
+
+// attribute converts an attribute value with the type of its declaration
+func (d *decoder) attribute(a xml.Attr, uses []*xsdAttribute, ns namespaces) (any, bool) {
+	for _, use := range uses {
+		decl := d.set.attribute(use)
+		if decl.QName.Local != a.Name.Local || (decl.QName.Space != a.Name.Space && a.Name.Space != "") {
+			continue
+		}
+		if decl.SimpleType != nil {
+			return d.convert(a.Value, decl.SimpleType, xml.Name{}, ns, 0), true
+		}
+		_, st, builtin := d.set.namedType(decl.TypeName)
+		return d.convert(a.Value, st, builtin, ns, 0), true
+	}
+	return a.Value, false
+}
+
+// childElement finds the declaration of a child element in a content model,
+// repeated reports whether the element or one of the groups around it may occur more than once
+func (d *decoder) childElement(groups []*xsdModelGroup, name xml.Name, repeated bool, depth int) (*xsdElement, bool) {
+	if depth > 32 {
+		return nil, false
+	}
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		groupRepeated := repeated || occurs(g.MaxOccurs) != 1
+		for _, p := range g.Particles {
+			switch {
+			case p.Element != nil:
+				if decl := d.match(p.Element, name); decl != nil {
+					return decl, groupRepeated || occurs(p.Element.MaxOccurs) != 1
+				}
+			case p.Any != nil:
+				if wildcardAllows(p.Any, name) {
+					if decl := d.set.element(name); decl != nil {
+						return decl, groupRepeated || occurs(p.Any.MaxOccurs) != 1
+					}
+				}
+			case p.ModelGroup != nil:
+				if decl, rep := d.childElement([]*xsdModelGroup{p.ModelGroup}, name, groupRepeated, depth+1); decl != nil {
+					return decl, rep
+				}
+			case p.Group != nil:
+				ref := d.set.group(p.Group).modelGroup()
+				if decl, rep := d.childElement([]*xsdModelGroup{ref}, name, groupRepeated || occurs(p.Group.MaxOccurs) != 1, depth+1); decl != nil {
+					return decl, rep
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// convert converts a lexical value to a Go value of its simple type, see Node
+func (d *decoder) convert(value string, st *xsdSimpleType, builtin xml.Name, ns namespaces, depth int) any {
+	if st == nil || depth > 32 {
+		return convertBuiltin(value, builtin.Local, ns)
+	}
+	switch {
+	case st.Restriction != nil:
+		if st.Restriction.SimpleType != nil {
+			return d.convert(value, st.Restriction.SimpleType, xml.Name{}, ns, depth+1)
+		}
+		_, base, baseBuiltin := d.set.namedType(st.Restriction.BaseName)
+		return d.convert(value, base, baseBuiltin, ns, depth+1)
+	case st.List != nil:
+		item, itemBuiltin := st.List.SimpleType, xml.Name{}
+		if item == nil {
+			_, item, itemBuiltin = d.set.namedType(st.List.ItemTypeName)
+		}
+		items := []any{}
+		for _, field := range strings.Fields(value) {
+			items = append(items, d.convert(field, item, itemBuiltin, ns, depth+1))
+		}
+		return items
+	case st.Union != nil:
+		for _, member := range st.Union.MemberTypeNames {
+			_, memberType, memberBuiltin := d.set.namedType(member)
+			if d.checkValue(value, memberType, memberBuiltin, 0) == "" {
+				return d.convert(value, memberType, memberBuiltin, ns, depth+1)
+			}
+		}
+		for _, member := range st.Union.SimpleTypes {
+			if d.checkValue(value, member, xml.Name{}, 0) == "" {
+				return d.convert(value, member, xml.Name{}, ns, depth+1)
+			}
+		}
+	}
+	return value
+}
+
+// convertBuiltin converts a value of a built-in type, values that aren't valid are returned unchanged
+func convertBuiltin(value, typ string, ns namespaces) any {
+	if isStringType(typ) {
+		return value
+	}
+	collapsed := collapseWhitespace(value)
+	if checkBuiltin(collapsed, typ) != "" {
+		return value
+	}
+	switch typ {
+	case "boolean":
+		return collapsed == "true" || collapsed == "1"
+	case "decimal":
+		r, ok := new(big.Rat).SetString(collapsed)
+		if ok {
+			return r
+		}
+	case "float", "double":
+		f, err := strconv.ParseFloat(strings.TrimPrefix(collapsed, "+"), 64)
+		if err == nil {
+			return f
+		}
+	case "dateTime", "date", "time":
+		t, err := parseTime(collapsed, timeLayouts[typ])
+		if err == nil {
+			return t
+		}
+	case "base64Binary":
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+		if err == nil {
+			return b
+		}
+	case "hexBinary":
+		b, err := hex.DecodeString(collapsed)
+		if err == nil {
+			return b
+		}
+	case "duration":
+		d, err := xsd.ParseDuration(collapsed)
+		if err == nil {
+			return d
+		}
+	case "QName":
+		return ns.resolve(collapsed)
+	}
+	if _, ok := integerRanges[typ]; ok || typ == "integer" {
+		i, ok := new(big.Int).SetString(strings.TrimPrefix(collapsed, "+"), 10)
+		if !ok {
+			return value
+		}
+		if i.IsInt64() {
+			return i.Int64()
+		}
+		return i
+	}
+	if _, ok := builtinPatterns[typ]; ok {
+		return collapsed
+	}
+	return value
+}
@@ -0,0 +1,81 @@
+package gosoap
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <soap:Body>
+    <GetOrderResponse xmlns="http://example.com/order">
+      <OrderID>9007199254740993</OrderID>
+      <Placed>2024-03-01T10:30:00Z</Placed>
+      <Paid>1</Paid>
+      <Total currency="EUR">12.10</Total>
+      <Tags>1 2 3</Tags>
+      <Item sku="a"><Quantity>2</Quantity></Item>
+      <Note xsi:nil="true"/>
+    </GetOrderResponse>
+  </soap:Body>
+</soap:Envelope>`)
+	}))
+	t.Cleanup(server.Close)
+	client := newTestClient(t, "./testdata/order.wsdl", server, Config{})
+
+	res, err := client.Call(context.Background(), "GetOrder", Params{"OrderID": 1})
+	require.NoError(t, err)
+	root, err := res.Decode()
+	require.NoError(t, err)
+
+	order := root.Find("GetOrderResponse")
+	require.Len(t, order, 1)
+	assert.Equal(t, xml.Name{Space: "http://example.com/order", Local: "GetOrderResponse"}, order[0].Name)
+
+	assert.Equal(t, int64(9007199254740993), root.Get("GetOrderResponse/OrderID"))
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC), root.Get("GetOrderResponse/Placed"))
+	assert.Equal(t, true, root.Get("GetOrderResponse/Paid"))
+	assert.Equal(t, big.NewRat(121, 10), root.Get("GetOrderResponse/Total"))
+	assert.Equal(t, "EUR", root.Get("GetOrderResponse/Total/@currency"))
+	assert.Equal(t, []any{int64(1), int64(2), int64(3)}, root.Get("GetOrderResponse/Tags"))
+	assert.Nil(t, root.Get("GetOrderResponse/Note"))
+	assert.True(t, root.Find("GetOrderResponse/Note")[0].Nil)
+	assert.Nil(t, root.Get("GetOrderResponse/Missing"))
+
+	// repeated elements are lists even if they occur once
+	items, ok := root.Get("GetOrderResponse/Item").([]any)
+	require.True(t, ok)
+	require.Len(t, items, 1)
+	item := items[0].(*Node)
+	assert.Equal(t, xml.Name{Space: "http://example.com/order", Local: "Item"}, item.Type)
+	assert.Equal(t, "a", item.Attrs["sku"])
+	assert.Equal(t, int64(2), item.Get("Quantity"))
+	assert.Equal(t, int64(2), root.Get("GetOrderResponse/Item[1]/Quantity"))
+	assert.Equal(t, int64(2), root.Get("*/Item/Quantity"))
+}
+
+func TestDecodeWithoutSchema(t *testing.T) {
+	t.Parallel()
+	res := &Response{Body: []byte(`<a:Result xmlns:a="urn:a" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<Count xsi:type="xsd:int">3</Count><Name>x</Name><Name>y</Name></a:Result>`)}
+	root, err := res.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), root.Get("Result/Count"), "xsi:type is used for undeclared elements")
+	assert.Equal(t, []any{"x", "y"}, root.Get("Result/Name"))
+	assert.Equal(t, "y", root.Get("Result/Name[2]"))
+
+	res = &Response{Body: []byte(`<soap:Fault xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><faultcode>soap:Server</faultcode><faultstring>boom</faultstring></soap:Fault>`)}
+	_, err = res.Decode()
+	assert.ErrorAs(t, err, &FaultError{})
+}
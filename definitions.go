@@ -0,0 +1,298 @@
+package gosoap
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+)
+
+// Definitions is a read-only description of a WSDL and the documents it imports.
+// It is a snapshot, changing it has no effect on a Client.
+// see https://www.w3.org/TR/2001/NOTE-wsdl-20010315
+type Definitions struct {
+	Name            string
+	TargetNamespace string
+	Services        []*Service
+	Bindings        []*Binding
+	Messages        []*Message
+	// Schema holds the components of all schemas of the types sections and the schemas they import
+	Schema *Schema
+}
+
+// Service returns the service with the given name or nil
+func (d *Definitions) Service(name string) *Service {
+	for _, s := range d.Services {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Binding returns the binding with the given name or nil
+func (d *Definitions) Binding(name xml.Name) *Binding {
+	for _, b := range d.Bindings {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// Message returns the message with the given name or nil
+func (d *Definitions) Message(name xml.Name) *Message {
+	for _, m := range d.Messages {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+type Service struct {
+	Name  string
+	Ports []*Port
+}
+
+// Port returns the port with the given name or nil
+func (s *Service) Port(name string) *Port {
+	for _, p := range s.Ports {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+type Port struct {
+	Name string
+	// Address is the location of the soap:address of the port
+	Address string
+	Binding *Binding
+}
+
+// Binding is a SOAP binding of the operations of a port type
+type Binding struct {
+	Name     xml.Name
+	PortType xml.Name
+	// Style is the default style of the operations, "document" or "rpc"
+	Style      string
+	Transport  string
+	Operations []*Operation
+}
+
+// Operation returns the operation with the given name or nil, it returns the first of overloaded operations
+func (b *Binding) Operation(name string) *Operation {
+	for _, o := range b.Operations {
+		if o.Name == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// Operation combines an operation of a port type with its SOAP binding
+type Operation struct {
+	Name       string
+	SOAPAction string
+	// Style is "document" or "rpc"
+	Style  string
+	Input  *OperationMessage
+	Output *OperationMessage
+	Faults []*OperationFault
+}
+
+// OperationMessage is the input or output of an operation
+type OperationMessage struct {
+	// Name distinguishes overloaded operations, it defaults to the operation name with the suffix "Request" or "Response"
+	Name    string
+	Message *Message
+	// Action is the WS-Addressing action
+	Action  string
+	Body    *BodyBinding
+	Headers []*HeaderBinding
+}
+
+// BodyBinding describes how the message parts appear in the SOAP body
+// see https://www.w3.org/TR/2001/NOTE-wsdl-20010315#_soap:body
+type BodyBinding struct {
+	// Parts that appear in the body, all parts of the message if empty
+	Parts []string
+	// Use is "literal" or "encoded"
+	Use           string
+	EncodingStyle string
+	Namespace     string
+}
+
+// HeaderBinding is a message part that is sent as SOAP header block
+// see https://www.w3.org/TR/2001/NOTE-wsdl-20010315#_soap:header
+type HeaderBinding struct {
+	Message       *Message
+	Part          string
+	Use           string
+	EncodingStyle string
+	Namespace     string
+}
+
+type OperationFault struct {
+	Name    string
+	Message *Message
+	Action  string
+}
+
+type Message struct {
+	Name  xml.Name
+	Parts []*MessagePart
+}
+
+// Part returns the part with the given name or nil
+func (m *Message) Part(name string) *MessagePart {
+	for _, p := range m.Parts {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// MessagePart references either a global element or a type
+type MessagePart struct {
+	Name    string
+	Element xml.Name
+	Type    xml.Name
+}
+
+// ParseWSDL loads a WSDL and the documents it imports, like NewClient it accepts any WSDLSource,
+// e.g. SourceFromURI or SourceFromBytes
+func ParseWSDL(source WSDLSource) (*Definitions, error) {
+	d, err := getWSDLDefinitions(context.Background(), source, &Config{})
+	if err != nil {
+		return nil, err
+	}
+	return newDefinitions(d), nil
+}
+
+// Definitions returns a description of the WSDL the client was created from,
+// nil if the client has no WSDL loaded
+func (c *Client) Definitions() *Definitions {
+	d := c.state().definitions
+	if d == nil {
+		return nil
+	}
+	return newDefinitions(d)
+}
+
+func newDefinitions(d *wsdlDefinitions) *Definitions {
+	defs := &Definitions{
+		Name:            d.Name,
+		TargetNamespace: d.TargetNamespace,
+		Schema:          newSchema(d.schemas),
+	}
+
+	messages := map[xml.Name]*Message{}
+	for _, m := range d.Messages {
+		message := &Message{Name: m.QName}
+		for _, p := range m.Parts {
+			message.Parts = append(message.Parts, &MessagePart{Name: p.Name, Element: p.ElementName, Type: p.TypeName})
+		}
+		messages[message.Name] = message
+		defs.Messages = append(defs.Messages, message)
+	}
+
+	portTypes := map[xml.Name]*wsdlPortTypes{}
+	for _, pt := range d.PortTypes {
+		portTypes[pt.QName] = pt
+	}
+	bindings := map[xml.Name]*Binding{}
+	for _, b := range d.Bindings {
+		binding := &Binding{Name: b.QName, PortType: b.TypeName, Style: b.style(nil)}
+		if len(b.SoapBindings) > 0 {
+			binding.Transport = b.SoapBindings[0].Transport
+		}
+		for _, o := range b.Operations {
+			binding.Operations = append(binding.Operations, newOperation(b, o, portTypes[b.TypeName], messages))
+		}
+		bindings[binding.Name] = binding
+		defs.Bindings = append(defs.Bindings, binding)
+	}
+
+	for _, s := range d.Services {
+		service := &Service{Name: s.Name}
+		for _, p := range s.Ports {
+			port := &Port{Name: p.Name, Binding: bindings[p.BindingName]}
+			if len(p.SoapAddresses) > 0 {
+				port.Address = p.SoapAddresses[0].Location
+			}
+			service.Ports = append(service.Ports, port)
+		}
+		defs.Services = append(defs.Services, service)
+	}
+	return defs
+}
+
+func newOperation(b *wsdlBinding, o *wsdlOperation, pt *wsdlPortTypes, messages map[xml.Name]*Message) *Operation {
+	op := &Operation{Name: o.Name, Style: b.style(o)}
+	if len(o.SoapOperations) > 0 {
+		op.SOAPAction = o.SoapOperations[0].SoapAction
+	}
+
+	var abstract *wsdlOperation
+	if pt != nil {
+		input, output := o.declaredNames()
+		abstract, _ = pt.operation(o.Name, input, output)
+	}
+	if abstract == nil {
+		return op
+	}
+	if len(abstract.Inputs) > 0 {
+		op.Input = &OperationMessage{
+			Name:    abstract.inputName(),
+			Message: messages[abstract.Inputs[0].MessageName],
+			Action:  abstract.Inputs[0].WsawAction,
+		}
+		if len(o.Inputs) > 0 {
+			op.Input.Body, op.Input.Headers = bodyBinding(o.Inputs[0].SoapBodies), headerBindings(o.Inputs[0].SoapHeaders, messages)
+		}
+	}
+	if len(abstract.Outputs) > 0 {
+		op.Output = &OperationMessage{
+			Name:    abstract.outputName(),
+			Message: messages[abstract.Outputs[0].MessageName],
+			Action:  abstract.Outputs[0].WsawAction,
+		}
+		if len(o.Outputs) > 0 {
+			op.Output.Body, op.Output.Headers = bodyBinding(o.Outputs[0].SoapBodies), headerBindings(o.Outputs[0].SoapHeaders, messages)
+		}
+	}
+	for _, f := range abstract.Faults {
+		op.Faults = append(op.Faults, &OperationFault{Name: f.Name, Message: messages[f.MessageName], Action: f.WsawAction})
+	}
+	return op
+}
+
+func bodyBinding(bodies []*soapBody) *BodyBinding {
+	if len(bodies) == 0 {
+		return nil
+	}
+	b := bodies[0]
+	return &BodyBinding{
+		Parts:         strings.Fields(b.Parts),
+		Use:           b.Use,
+		EncodingStyle: b.EncodingStyle,
+		Namespace:     b.Namespace,
+	}
+}
+
+func headerBindings(headers []*soapHeader, messages map[xml.Name]*Message) []*HeaderBinding {
+	var bindings []*HeaderBinding
+	for _, h := range headers {
+		bindings = append(bindings, &HeaderBinding{
+			Message:       messages[h.MessageName],
+			Part:          h.Part,
+			Use:           h.Use,
+			EncodingStyle: h.EncodingStyle,
+			Namespace:     h.Namespace,
+		})
+	}
+	return bindings
+}
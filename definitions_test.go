@@ -0,0 +1,140 @@
+package gosoap
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func quoteName(local string) xml.Name {
+	return xml.Name{Space: "http://example.com/quote", Local: local}
+}
+
+func TestParseWSDLFromURI(t *testing.T) {
+	t.Parallel()
+	dir, err := filepath.Abs("testdata/split")
+	require.NoError(t, err)
+	d, err := ParseWSDL(SourceFromURI("file://" + filepath.Join(dir, "main.wsdl")))
+	require.NoError(t, err)
+	assert.Len(t, d.Messages, 2, "imports are followed")
+	assert.NotEmpty(t, d.Bindings)
+}
+
+func TestParseWSDL(t *testing.T) {
+	t.Parallel()
+	spec, err := os.ReadFile("testdata/quote.wsdl")
+	require.NoError(t, err)
+	d, err := ParseWSDL(SourceFromBytes(spec))
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://example.com/quote", d.TargetNamespace)
+	service := d.Service("QuoteService")
+	require.NotNil(t, service)
+	port := service.Port("QuotePort")
+	require.NotNil(t, port)
+	assert.Equal(t, "http://example.com/quote", port.Address)
+
+	binding := port.Binding
+	require.NotNil(t, binding)
+	assert.Same(t, binding, d.Binding(quoteName("QuoteBinding")))
+	assert.Equal(t, quoteName("QuotePortType"), binding.PortType)
+	assert.Equal(t, "document", binding.Style)
+	assert.Equal(t, "http://schemas.xmlsoap.org/soap/http", binding.Transport)
+
+	var operations []string
+	for _, o := range binding.Operations {
+		operations = append(operations, o.Name)
+	}
+	assert.Equal(t, []string{"GetQuote", "Convert", "GetSecureQuote"}, operations)
+
+	op := binding.Operation("GetSecureQuote")
+	require.NotNil(t, op)
+	assert.Equal(t, "http://example.com/quote/GetSecureQuote", op.SOAPAction)
+	assert.Equal(t, "document", op.Style)
+	require.NotNil(t, op.Input)
+	assert.Same(t, d.Message(quoteName("GetSecureQuoteIn")), op.Input.Message)
+	assert.Equal(t, &BodyBinding{Parts: []string{"parameters"}, Use: "literal"}, op.Input.Body)
+	require.Len(t, op.Input.Headers, 1)
+	assert.Same(t, op.Input.Message, op.Input.Headers[0].Message)
+	assert.Equal(t, "auth", op.Input.Headers[0].Part)
+	assert.Equal(t, quoteName("Auth"), op.Input.Message.Part("auth").Element)
+	require.NotNil(t, op.Output)
+	assert.Equal(t, quoteName("GetQuoteOut"), op.Output.Message.Name)
+
+	request := d.Schema.Element(op.Input.Message.Part("parameters").Element)
+	require.NotNil(t, request)
+	require.NotNil(t, request.ComplexType)
+	symbol := request.ComplexType.Content.Particles[0].Element
+	assert.Equal(t, &Element{
+		Name:      quoteName("Symbol"),
+		Type:      xml.Name{Space: xsdNS, Local: "string"},
+		MinOccurs: 1,
+		MaxOccurs: 1,
+	}, symbol)
+}
+
+func TestDefinitionsSchema(t *testing.T) {
+	t.Parallel()
+	d, err := ParseWSDL(SourceFromBytes([]byte(schemaTestWSDL)))
+	require.NoError(t, err)
+	schema := d.Schema
+
+	customer := schema.ComplexType(shopName("Customer"))
+	require.NotNil(t, customer)
+	assert.Equal(t, shopName("Party"), customer.Base)
+	assert.Equal(t, "extension", customer.Derivation)
+	require.Len(t, customer.Content.Particles, 1)
+	payment := customer.Content.Particles[0].Element
+	assert.True(t, payment.Ref)
+	assert.True(t, payment.Abstract)
+	assert.Equal(t, 0, payment.MinOccurs)
+	assert.Equal(t, Unbounded, payment.MaxOccurs)
+	require.Len(t, customer.Attributes, 1)
+	assert.Equal(t, xml.Name{Local: "vip"}, customer.Attributes[0].Name)
+
+	party := schema.ComplexType(shopName("Party"))
+	require.NotNil(t, party)
+	contact := party.Content.Particles[1].Group
+	assert.Equal(t, shopName("Contact"), contact.Ref)
+	assert.Equal(t, "choice", contact.Kind)
+	assert.Len(t, contact.Particles, 2)
+	require.Len(t, party.Attributes, 1)
+	assert.Equal(t, &Attribute{Name: xml.Name{Local: "createdBy"}, Type: xml.Name{Space: xsdNS, Local: "string"}, Required: true}, party.Attributes[0])
+
+	price := schema.ComplexType(shopName("Price"))
+	assert.True(t, price.SimpleContent)
+	assert.Equal(t, shopName("Amount"), price.Base)
+
+	two := 2
+	assert.Equal(t, &SimpleType{
+		Name:    shopName("Amount"),
+		Variety: "atomic",
+		Base:    xml.Name{Space: xsdNS, Local: "decimal"},
+		Facets:  Facets{MinInclusive: "0", FractionDigits: &two},
+	}, schema.SimpleType(shopName("Amount")))
+	assert.Equal(t, "list", schema.SimpleType(shopName("Tags")).Variety)
+	assert.Equal(t, []string{"S", "M", "L"}, schema.SimpleType(shopName("Label")).Facets.Enumeration)
+
+	note := schema.Element(shopName("Note"))
+	require.NotNil(t, note)
+	assert.Equal(t, "all", note.ComplexType.Content.Kind)
+	assert.Equal(t, &Wildcard{Namespace: "##other", ProcessContents: "lax", MinOccurs: 0, MaxOccurs: 1}, note.ComplexType.Content.Particles[1].Any)
+}
+
+func TestClientDefinitions(t *testing.T) {
+	t.Parallel()
+	server, _ := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/quote.wsdl", server, Config{})
+
+	d := client.Definitions()
+	assert.Len(t, d.Messages, 5)
+	// every call returns a new snapshot
+	d.Bindings = nil
+	assert.Len(t, client.Definitions().Bindings, 1)
+
+	assert.Nil(t, (&Client{}).Definitions())
+}
@@ -5,29 +5,33 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 )
 
 // MarshalXML envelope the body and encode to xml
 func (p *process) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
-	segments := &tokenData{}
+	segments := &tokenData{types: p.config.Types, prefixes: namespacePrefixes{}}
+	scopes.Store(e, &coderScope{types: p.config.Types.orDefault(), prefixes: segments.prefixes})
+	defer scopes.Delete(e)
 
 	segments.startEnvelope(p.config)
 
 	if p.request.HeaderEntries != nil {
-		segments.startHeader(p.namespace, p.config)
-		segments.recursiveEncode(p.request.HeaderEntries)
+		segments.startHeader(p.config)
+		if err := segments.encodeHeaderEntries(p.request.HeaderEntries, p.config); err != nil {
+			return err
+		}
 		segments.endHeader(p.config)
 	}
 
-	err := segments.startBody(p.request.WSDLOperation, p.namespace, p.config)
+	segments.startBody(p.config)
+	err := segments.encodeBody(p.request.Body, p.layout, p.config)
 	if err != nil {
 		return err
 	}
 
-	segments.recursiveEncode(p.request.Body)
-
 	// end envelope
-	segments.endBody(p.request.WSDLOperation, p.config)
+	segments.endBody(p.config)
 	segments.endEnvelope(p.config)
 
 	for _, t := range segments.data {
@@ -49,6 +53,10 @@ func (p *process) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
 
 type tokenData struct {
 	data []segment
+	// types are the registered types of the client, nil for the default registry
+	types *TypeRegistry
+	// prefixes are the prefixes allocated for the xsi:type values of the envelope
+	prefixes namespacePrefixes
 }
 
 type NamedElement interface {
@@ -163,20 +171,64 @@ func (tokens *tokenData) endEnvelope(c *Config) {
 	tokens.data = append(tokens.data, segment{token: e})
 }
 
-func (tokens *tokenData) startHeader(namespace string, c *Config) {
+func (tokens *tokenData) startHeader(c *Config) {
 	h := xml.StartElement{
 		Name: xml.Name{
 			Space: "",
 			Local: fmt.Sprintf("%s:Header", c.EnvelopePrefix),
 		},
-		Attr: []xml.Attr{
-			{Name: xml.Name{Space: "", Local: "xmlns"}, Value: namespace},
-		},
 	}
 
 	tokens.data = append(tokens.data, segment{token: h})
 }
 
+// encodeHeaderEntries writes the header blocks, HeaderEntry values get their namespace and SOAP attributes,
+// everything else is encoded like the body.
+func (tokens *tokenData) encodeHeaderEntries(entries []any, c *Config) error {
+	for _, entry := range entries {
+		var h HeaderEntry
+		switch e := entry.(type) {
+		case HeaderEntry:
+			h = e
+		case *HeaderEntry:
+			h = *e
+		default:
+			tokens.recursiveEncode(entry)
+			continue
+		}
+
+		name, err := h.name()
+		if err != nil {
+			return err
+		}
+		start := xml.StartElement{
+			Name: xml.Name{Space: "", Local: name.Local},
+			Attr: h.attrs(c),
+		}
+		if name.Space != "" {
+			start.Attr = append([]xml.Attr{{Name: xml.Name{Space: "", Local: "xmlns"}, Value: name.Space}}, start.Attr...)
+		}
+		if h.Name == "" {
+			// the content marshals itself as the header block
+			content := h.Content
+			if n, ok := content.(NamedElement); ok {
+				for _, a := range n.Name().Attr {
+					if a.Name.Local != "xmlns" && a.Name.Space != "xmlns" {
+						start.Attr = append(start.Attr, a)
+					}
+				}
+				content = n.Value()
+			}
+			tokens.data = append(tokens.data, segment{namedValue: named{content: content, start: start}})
+			continue
+		}
+		tokens.data = append(tokens.data, segment{token: start})
+		tokens.recursiveEncode(h.Content)
+		tokens.data = append(tokens.data, segment{token: start.End()})
+	}
+	return nil
+}
+
 func (tokens *tokenData) endHeader(c *Config) {
 	h := xml.EndElement{
 		Name: xml.Name{
@@ -188,7 +240,7 @@ func (tokens *tokenData) endHeader(c *Config) {
 	tokens.data = append(tokens.data, segment{token: h})
 }
 
-func (tokens *tokenData) startBody(wsdlOperation, namespace string, c *Config) error {
+func (tokens *tokenData) startBody(c *Config) {
 	b := xml.StartElement{
 		Name: xml.Name{
 			Space: "",
@@ -196,42 +248,259 @@ func (tokens *tokenData) startBody(wsdlOperation, namespace string, c *Config) e
 		},
 	}
 
-	if wsdlOperation == "" {
-		return fmt.Errorf("operation is empty")
-	} else if namespace == "" {
-		return fmt.Errorf("namespace is empty")
-	}
+	tokens.data = append(tokens.data, segment{token: b})
+}
 
-	r := xml.StartElement{
+// endBody close body of the envelope
+func (tokens *tokenData) endBody(c *Config) {
+	b := xml.EndElement{
 		Name: xml.Name{
 			Space: "",
-			Local: wsdlOperation,
-		},
-		Attr: []xml.Attr{
-			{Name: xml.Name{Space: "", Local: "xmlns"}, Value: namespace},
+			Local: fmt.Sprintf("%s:Body", c.EnvelopePrefix),
 		},
 	}
 
-	tokens.data = append(tokens.data, segment{token: b}, segment{token: r})
-
-	return nil
+	tokens.data = append(tokens.data, segment{token: b})
 }
 
-// endToken close body of the envelope
-func (tokens *tokenData) endBody(wsdlOperation string, c *Config) {
-	b := xml.EndElement{
+// startElement opens an element, declaring its namespace as the default namespace
+func (tokens *tokenData) startElement(name xml.Name) {
+	e := xml.StartElement{
 		Name: xml.Name{
 			Space: "",
-			Local: fmt.Sprintf("%s:Body", c.EnvelopePrefix),
+			Local: name.Local,
 		},
 	}
+	if name.Space != "" {
+		e.Attr = []xml.Attr{{Name: xml.Name{Space: "", Local: "xmlns"}, Value: name.Space}}
+	}
 
-	r := xml.EndElement{
+	tokens.data = append(tokens.data, segment{token: e})
+}
+
+func (tokens *tokenData) endElement(name xml.Name) {
+	e := xml.EndElement{
 		Name: xml.Name{
 			Space: "",
-			Local: wsdlOperation,
+			Local: name.Local,
 		},
 	}
 
-	tokens.data = append(tokens.data, segment{token: r}, segment{token: b})
+	tokens.data = append(tokens.data, segment{token: e})
+}
+
+// bodyLayout describes how the request body is placed inside the SOAP body
+type bodyLayout struct {
+	// wrapper is the element the whole body is wrapped in, nothing is written if the local name is empty
+	wrapper xml.Name
+	// rpc wrappers are written with a prefix so the part accessors inside them stay unqualified
+	rpc bool
+	// encodingStyle is set if the body uses SOAP encoding instead of being literal
+	encodingStyle string
+	// parts of the input message. For document style messages with more than one part,
+	// the content of every part is looked up in the body by the part name and wrapped in the part element.
+	// For RPC style messages the parts are written as accessors of the wrapper in message order.
+	parts []bodyPart
+}
+
+type bodyPart struct {
+	name    string
+	element xml.Name
+	typ     xml.Name
+}
+
+// rpcPrefix is used for the namespace of RPC wrapper elements
+const rpcPrefix = "m"
+
+func (tokens *tokenData) encodeBody(body any, layout bodyLayout, c *Config) error {
+	encoded := layout.encodingStyle != ""
+	switch {
+	case layout.rpc:
+		start := xml.StartElement{
+			Name: xml.Name{Space: "", Local: rpcPrefix + ":" + layout.wrapper.Local},
+			Attr: []xml.Attr{{Name: xml.Name{Space: "", Local: "xmlns:" + rpcPrefix}, Value: layout.wrapper.Space}},
+		}
+		if encoded {
+			start.Attr = append(start.Attr, encodingAttrs(layout.encodingStyle, c)...)
+		}
+		tokens.data = append(tokens.data, segment{token: start})
+		if hasParts(body, layout.parts) {
+			for _, part := range layout.parts {
+				v, _ := partValue(body, part.name)
+				if err := tokens.encodeAccessor(part.name, v, part.typ, encoded); err != nil {
+					return err
+				}
+			}
+		} else if err := tokens.encodeContent(body, encoded); err != nil {
+			return err
+		}
+		tokens.data = append(tokens.data, segment{token: start.End()})
+	case len(layout.parts) > 0:
+		for _, part := range layout.parts {
+			v, ok := partValue(body, part.name)
+			if !ok {
+				return fmt.Errorf("body has no value for message part %q", part.name)
+			}
+			tokens.startElement(part.element)
+			tokens.recursiveEncode(v)
+			tokens.endElement(part.element)
+		}
+	case layout.wrapper.Local != "":
+		tokens.startElement(layout.wrapper)
+		if encoded {
+			start := &tokens.data[len(tokens.data)-1]
+			s := start.token.(xml.StartElement)
+			s.Attr = append(s.Attr, encodingAttrs(layout.encodingStyle, c)...)
+			start.token = s
+		}
+		if err := tokens.encodeContent(body, encoded); err != nil {
+			return err
+		}
+		tokens.endElement(layout.wrapper)
+	default:
+		tokens.recursiveEncode(body)
+	}
+	return nil
+}
+
+// encodeAccessor writes a value wrapped in an unqualified element
+func (tokens *tokenData) encodeAccessor(name string, v any, typ xml.Name, encoded bool) error {
+	if encoded {
+		return tokens.encodeEncoded(name, reflect.ValueOf(v), typ)
+	}
+	tokens.startElement(xml.Name{Local: name})
+	tokens.recursiveEncode(v)
+	tokens.endElement(xml.Name{Local: name})
+	return nil
+}
+
+func (tokens *tokenData) encodeContent(body any, encoded bool) error {
+	if encoded {
+		return tokens.encodeEncodedFields(reflect.ValueOf(body))
+	}
+	tokens.recursiveEncode(body)
+	return nil
+}
+
+// hasParts reports whether the body contains a value for every part
+func hasParts(body any, parts []bodyPart) bool {
+	if len(parts) == 0 {
+		return false
+	}
+	for _, part := range parts {
+		if _, ok := partValue(body, part.name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// partValue looks up the content of a message part in a map or ArrayParams body
+func partValue(body any, part string) (any, bool) {
+	v := reflect.ValueOf(body)
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		value := v.MapIndex(reflect.ValueOf(part).Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return nil, false
+		}
+		return value.Interface(), true
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			pair, ok := v.Index(i).Interface().([2]any)
+			if ok && pair[0] == part {
+				return pair[1], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// headerEntryNames returns the names of the header blocks written for the header entries of a request.
+// Map keys and ArrayParams labels are unqualified.
+func headerEntryNames(entries []any) []xml.Name {
+	var names []xml.Name
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case HeaderEntry:
+			if name, err := e.name(); err == nil {
+				names = append(names, name)
+			}
+			continue
+		case *HeaderEntry:
+			if name, err := e.name(); err == nil {
+				names = append(names, name)
+			}
+			continue
+		}
+		if name, ok := elementName(entry); ok {
+			names = append(names, name)
+			continue
+		}
+		v := reflect.ValueOf(entry)
+		switch {
+		case !v.IsValid():
+		case v.Kind() == reflect.Map:
+			for _, key := range v.MapKeys() {
+				names = append(names, xml.Name{Local: key.String()})
+			}
+		case v.Type() == arrayParamType:
+			for _, pair := range entry.(ArrayParams) {
+				if label, ok := pair[0].(string); ok {
+					names = append(names, xml.Name{Local: label})
+				}
+			}
+		case v.Kind() == reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				names = append(names, headerEntryNames([]any{v.Index(i).Interface()})...)
+			}
+		}
+	}
+	return names
+}
+
+// containsName reports whether a name is in the list, unqualified names match any namespace
+func containsName(names []xml.Name, name xml.Name) bool {
+	for _, n := range names {
+		if n.Local == name.Local && (n.Space == "" || n.Space == name.Space) {
+			return true
+		}
+	}
+	return false
+}
+
+// elementName returns the name of the element a value marshals itself as,
+// this is the case for NamedElements and structs with a named XMLName field.
+func elementName(v any) (xml.Name, bool) {
+	if named, ok := v.(NamedElement); ok {
+		return named.Name().Name, true
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return xml.Name{}, false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return xml.Name{}, false
+	}
+	field, ok := rv.Type().FieldByName("XMLName")
+	if !ok {
+		return xml.Name{}, false
+	}
+	if name, ok := rv.FieldByIndex(field.Index).Interface().(xml.Name); ok && name.Local != "" {
+		return name, true
+	}
+	tag, _, _ := strings.Cut(field.Tag.Get("xml"), ",")
+	if tag == "" {
+		return xml.Name{}, false
+	}
+	if space, local, found := strings.Cut(tag, " "); found {
+		return xml.Name{Space: space, Local: local}, true
+	}
+	return xml.Name{Local: tag}, true
 }
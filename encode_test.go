@@ -9,9 +9,12 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/SoMuchForSubtlety/gosoap/xsd"
 )
 
 var (
@@ -100,7 +103,7 @@ func TestEncoding(t *testing.T) {
 			},
 			expectedAction: "http://lavasoft.com/GetIpLocation",
 			expectedBody: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
-    <soap:Header xmlns="http://lavasoft.com/">
+    <soap:Header>
         <TestHeader>
             <Value1>testing</Value1>
             <Value2>123</Value2>
@@ -126,7 +129,7 @@ func TestEncoding(t *testing.T) {
 			},
 			expectedAction: "http://lavasoft.com/GetIpLocation",
 			expectedBody: `<custom:Envelope test="param">
-    <custom:Header xmlns="http://lavasoft.com/">
+    <custom:Header>
         <h>value</h>
     </custom:Header>
     <custom:Body>
@@ -137,6 +140,63 @@ func TestEncoding(t *testing.T) {
 </custom:Envelope>`,
 		},
 
+		{
+			name: "header entries",
+			body: Params{"sIp": "127.0.0.1"},
+			headers: []any{
+				HeaderEntry{
+					Namespace:      "urn:vendor",
+					MustUnderstand: true,
+					Actor:          "http://schemas.xmlsoap.org/soap/actor/next",
+					Content:        TestHeader{Value1: "testing", Value2: 123},
+				},
+				HeaderEntry{
+					Name:      "Session",
+					Namespace: "urn:session",
+					Content:   Params{"ID": "abc"},
+				},
+			},
+			expectedAction: "http://lavasoft.com/GetIpLocation",
+			expectedBody: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+    <soap:Header>
+        <TestHeader xmlns="urn:vendor" soap:mustUnderstand="1" soap:actor="http://schemas.xmlsoap.org/soap/actor/next">
+            <Value1>testing</Value1>
+            <Value2>123</Value2>
+        </TestHeader>
+        <Session xmlns="urn:session">
+            <ID>abc</ID>
+        </Session>
+    </soap:Header>
+    <soap:Body>
+        <GetIpLocation xmlns="http://lavasoft.com/">
+            <sIp>127.0.0.1</sIp>
+        </GetIpLocation>
+    </soap:Body>
+</soap:Envelope>`,
+		},
+		{
+			name: "named header entry",
+			body: Params{"sIp": "127.0.0.1"},
+			headers: []any{
+				HeaderEntry{
+					Namespace:      "urn:token",
+					MustUnderstand: true,
+					Actor:          "http://example.com/gateway",
+					Content:        Named("secret", "Token"),
+				},
+			},
+			expectedAction: "http://lavasoft.com/GetIpLocation",
+			expectedBody: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+    <soap:Header>
+        <Token xmlns="urn:token" soap:mustUnderstand="1" soap:actor="http://example.com/gateway">secret</Token>
+    </soap:Header>
+    <soap:Body>
+        <GetIpLocation xmlns="http://lavasoft.com/">
+            <sIp>127.0.0.1</sIp>
+        </GetIpLocation>
+    </soap:Body>
+</soap:Envelope>`,
+		},
 		{
 			name:           "auto action",
 			body:           Params{"sIp": "127.0.0.1"},
@@ -157,7 +217,7 @@ func TestEncoding(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
 		body, err := io.ReadAll(r.Body)
-		require.NoError(t, err)
+		assert.NoError(t, err)
 		reqBody = body
 		header = r.Header
 		resp := `<?xml version="1.0" encoding="utf-8"?>
@@ -169,7 +229,7 @@ func TestEncoding(t *testing.T) {
 				</soap:Body>
 			</soap:Envelope>`
 		_, err = w.Write([]byte(resp))
-		require.NoError(t, err)
+		assert.NoError(t, err)
 	}))
 	defer server.Close()
 
@@ -193,3 +253,228 @@ func TestEncoding(t *testing.T) {
 		})
 	}
 }
+
+type GetQuoteRequest struct {
+	XMLName xml.Name `xml:"http://example.com/quote GetQuoteRequest"`
+	Symbol  string   `xml:"Symbol"`
+}
+
+// newCaptureServer starts a server that records the last request body and answers with an empty envelope
+func newCaptureServer(t *testing.T) (*httptest.Server, *[]byte) {
+	t.Helper()
+	var reqBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		reqBody = body
+		_, err = w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body/></soap:Envelope>`))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+	return server, &reqBody
+}
+
+func newTestClient(t *testing.T, wsdl string, server *httptest.Server, config Config) *Client {
+	t.Helper()
+	spec, err := os.ReadFile(wsdl)
+	require.NoError(t, err)
+	config.Client = server.Client()
+	client, err := NewClient(SourceFromBytes(spec), &config)
+	require.NoError(t, err)
+	setAddress(client, server.URL)
+	return client
+}
+
+// setAddress points a client to a test server
+func setAddress(c *Client, address string) {
+	s := *c.state()
+	s.address = address
+	c.wsdl.Store(&s)
+}
+
+func TestBodyLayout(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name         string
+		operation    string
+		body         any
+		expectedBody string
+		err          string
+	}{
+		{
+			name:      "element named differently from the operation",
+			operation: "GetQuote",
+			body:      Params{"Symbol": "ACME"},
+			expectedBody: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+    <soap:Body>
+        <GetQuoteRequest xmlns="http://example.com/quote">
+            <Symbol>ACME</Symbol>
+        </GetQuoteRequest>
+    </soap:Body>
+</soap:Envelope>`,
+		},
+		{
+			name:      "bare",
+			operation: "GetQuote",
+			body:      GetQuoteRequest{Symbol: "ACME"},
+			expectedBody: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+    <soap:Body>
+        <GetQuoteRequest xmlns="http://example.com/quote">
+            <Symbol>ACME</Symbol>
+        </GetQuoteRequest>
+    </soap:Body>
+</soap:Envelope>`,
+		},
+		{
+			name:      "multiple parts",
+			operation: "Convert",
+			body:      Params{"currency": "EUR", "symbol": "ACME"},
+			expectedBody: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+    <soap:Body>
+        <Symbol xmlns="http://example.com/quote">ACME</Symbol>
+        <Currency xmlns="http://example.com/quote">EUR</Currency>
+    </soap:Body>
+</soap:Envelope>`,
+		},
+		{
+			name:      "missing part",
+			operation: "Convert",
+			body:      ArrayParams{{"symbol", "ACME"}},
+			err:       `body has no value for message part "currency"`,
+		},
+	}
+
+	server, reqBody := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/quote.wsdl", server, Config{})
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.Call(context.Background(), tc.operation, tc.body)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedBody, string(*reqBody))
+		})
+	}
+}
+
+func TestRPCEncoded(t *testing.T) {
+	t.Parallel()
+	server, reqBody := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/rpc.wsdl", server, Config{})
+
+	_, err := client.Call(context.Background(), "findItems", Params{
+		"tags":     []string{"red", "blue"},
+		"limit":    10,
+		"category": "shoes",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+    <soap:Body>
+        <m:findItems xmlns:m="urn:inventory-service" soap:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/" xmlns:SOAP-ENC="http://schemas.xmlsoap.org/soap/encoding/">
+            <category xsi:type="xsd:string">shoes</category>
+            <limit xsi:type="xsd:int">10</limit>
+            <tags xsi:type="SOAP-ENC:Array" SOAP-ENC:arrayType="xsd:string[2]">
+                <item xsi:type="xsd:string">red</item>
+                <item xsi:type="xsd:string">blue</item>
+            </tags>
+        </m:findItems>
+    </soap:Body>
+</soap:Envelope>`, string(*reqBody))
+}
+
+func TestDecodeEncodedArray(t *testing.T) {
+	t.Parallel()
+	res := &Response{Body: []byte(`
+		<ns1:findItemsResponse xmlns:ns1="urn:inventory">
+			<findItemsReturn xsi:type="soapenc:Array" soapenc:arrayType="xsd:string[2]">
+				<findItemsReturn xsi:type="xsd:string">red</findItemsReturn>
+				<findItemsReturn xsi:type="xsd:string">blue</findItemsReturn>
+			</findItemsReturn>
+		</ns1:findItemsResponse>`)}
+
+	var out struct {
+		Return Array[string] `xml:"findItemsReturn"`
+	}
+	require.NoError(t, res.Unmarshal(&out))
+	assert.Equal(t, Array[string]{"red", "blue"}, out.Return)
+}
+
+type AuthHeader struct {
+	XMLName xml.Name `xml:"http://example.com/quote Auth"`
+	Token   string   `xml:"Token"`
+}
+
+func TestBindingHeaders(t *testing.T) {
+	t.Parallel()
+	server, reqBody := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/quote.wsdl", server, Config{})
+
+	_, err := client.Call(context.Background(), "GetSecureQuote", Params{"Symbol": "ACME"})
+	assert.EqualError(t, err, `operation "GetSecureQuote" requires the SOAP header "Auth" which is missing from the request`)
+
+	_, err = client.Call(context.Background(), "GetSecureQuote", Params{"Symbol": "ACME"}, AuthHeader{Token: "secret"})
+	require.NoError(t, err)
+	assert.Contains(t, string(*reqBody), `<soap:Body>
+        <GetQuoteRequest xmlns="http://example.com/quote">
+            <Symbol>ACME</Symbol>
+        </GetQuoteRequest>
+    </soap:Body>`)
+	assert.Contains(t, string(*reqBody), `<Token>secret</Token>`)
+}
+
+func TestRPCEncodedLibraryTypes(t *testing.T) {
+	t.Parallel()
+	server, reqBody := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/rpc.wsdl", server, Config{})
+
+	_, err := client.Call(context.Background(), "findItems", ArrayParams{
+		{"price", xsd.NewDecimal(150, 2)},
+		{"since", xsd.Date{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}},
+		{"checksum", xsd.HexBinary{0x0a, 0xff}},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(*reqBody), `<price xsi:type="xsd:decimal">1.50</price>`)
+	assert.Contains(t, string(*reqBody), `<since xsi:type="xsd:date">2024-03-01Z</since>`)
+	assert.Contains(t, string(*reqBody), `<checksum xsi:type="xsd:hexBinary">0AFF</checksum>`)
+}
+
+func TestBodyLayoutUndeclaredPrefix(t *testing.T) {
+	t.Parallel()
+	spec, err := os.ReadFile("./testdata/quote.wsdl")
+	require.NoError(t, err)
+	spec = bytes.Replace(spec, []byte(`name="symbol" element="tns:Symbol"`), []byte(`name="symbol" element="q:Symbol"`), 1)
+	server, _ := newCaptureServer(t)
+	client, err := NewClient(SourceFromBytes(spec), &Config{Client: server.Client()})
+	require.NoError(t, err)
+	setAddress(client, server.URL)
+
+	_, err = client.Call(context.Background(), "Convert", Params{"currency": "EUR", "symbol": "ACME"})
+	assert.EqualError(t, err, `message part "symbol" references "q:Symbol" with the undeclared namespace prefix "q"`)
+}
+
+func TestRPCEncodedUnsupportedValue(t *testing.T) {
+	t.Parallel()
+	server, _ := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/rpc.wsdl", server, Config{})
+
+	_, err := client.Call(context.Background(), "findItems", Params{
+		"category": "shoes",
+		"limit":    complex(1, 2),
+		"tags":     []string{"red"},
+	})
+	assert.EqualError(t, err, `can't encode "limit": values of type complex128 are not supported`)
+}
+
+func TestIgnoreMissingHeaders(t *testing.T) {
+	t.Parallel()
+	server, reqBody := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/quote.wsdl", server, Config{IgnoreMissingHeaders: true})
+
+	_, err := client.Call(context.Background(), "GetSecureQuote", Params{"Symbol": "ACME"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(*reqBody), "Auth")
+}
@@ -0,0 +1,210 @@
+package gosoap
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// roles with a predefined meaning
+// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383499 and https://www.w3.org/TR/soap12-part1/#soaproles
+const (
+	soap11ActorNext            = "http://schemas.xmlsoap.org/soap/actor/next"
+	soap12RoleNext             = "http://www.w3.org/2003/05/soap-envelope/role/next"
+	soap12RoleNone             = "http://www.w3.org/2003/05/soap-envelope/role/none"
+	soap12RoleUltimateReceiver = "http://www.w3.org/2003/05/soap-envelope/role/ultimateReceiver"
+)
+
+// HeaderProcessor processes a response header block, an error fails the request
+type HeaderProcessor func(ctx context.Context, block *HeaderBlock) error
+
+// MustUnderstandError is returned by Do if the response contains mandatory header blocks
+// targeting the client which have no HeaderProcessor
+// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383500 and https://www.w3.org/TR/soap12-part1/#soapmu
+type MustUnderstandError struct {
+	Headers []xml.Name
+}
+
+func (e *MustUnderstandError) Error() string {
+	names := make([]string, 0, len(e.Headers))
+	for _, name := range e.Headers {
+		names = append(names, formatName(name))
+	}
+	return fmt.Sprintf("the response contains mandatory header blocks which are not understood: %s", strings.Join(names, ", "))
+}
+
+// HeaderBlock is a header block of a response with its SOAP header attributes
+// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383497
+type HeaderBlock struct {
+	Name xml.Name
+	// Attrs are the attributes of the block element without namespace declarations
+	Attrs []xml.Attr
+	// MustUnderstand marks the header block as mandatory for the receiver
+	// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383500
+	MustUnderstand bool
+	// Actor is the SOAP 1.1 URI of the intended receiver
+	// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383499
+	Actor string
+	// Role is the SOAP 1.2 URI of the intended receiver
+	// see https://www.w3.org/TR/soap12-part1/#soaprole
+	Role string
+	// Relay marks a SOAP 1.2 header block to be relayed if it isn't processed
+	// see https://www.w3.org/TR/soap12-part1/#soaprelay
+	Relay bool
+	// XML is the header block element including the namespace declarations it uses
+	XML []byte
+
+	// types resolves Polymorphic values, nil for the default registry
+	types *TypeRegistry
+}
+
+// Unmarshal decodes the header block into v
+func (h *HeaderBlock) Unmarshal(v any) error {
+	return unmarshal(h.XML, v, h.types)
+}
+
+// targets reports whether the header block is meant for a receiver acting in roles besides the
+// ultimate receiver and next roles
+func (h *HeaderBlock) targets(roles []string) bool {
+	role := h.Role
+	if role == "" {
+		role = h.Actor
+	}
+	switch role {
+	case "", soap11ActorNext, soap12RoleNext, soap12RoleUltimateReceiver:
+		return true
+	case soap12RoleNone:
+		return false
+	}
+	return slices.Contains(roles, role)
+}
+
+// HeaderBlocks returns the header blocks of the response in document order.
+// Only the SOAP header attributes of the SOAP version of the response envelope are interpreted,
+// SOAP 1.1 is assumed if the envelope is unknown.
+func (r *Response) HeaderBlocks() ([]*HeaderBlock, error) {
+	if len(r.HeaderEntries) == 0 {
+		return nil, nil
+	}
+	nodes, err := fragmentElements(r.HeaderEntries, r.headerNamespaces, false)
+	if err != nil {
+		return nil, err
+	}
+	envelopeNS := r.envelopeNS
+	if envelopeNS == "" {
+		envelopeNS = soap11EnvelopeNS
+	}
+	soap12 := envelopeNS == soap12EnvelopeNS
+	blocks := make([]*HeaderBlock, 0, len(nodes))
+	for _, n := range nodes {
+		data, err := marshalXMLNodes([]*xmlNode{n})
+		if err != nil {
+			return nil, err
+		}
+		b := &HeaderBlock{Name: n.Name, XML: data, types: r.types}
+		for _, a := range n.Attr {
+			if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+				continue
+			}
+			b.Attrs = append(b.Attrs, a)
+			if a.Name.Space != envelopeNS {
+				continue
+			}
+			var valid bool
+			switch {
+			case a.Name.Local == "mustUnderstand":
+				b.MustUnderstand, valid = headerFlag(a.Value)
+			case a.Name.Local == "actor" && !soap12:
+				b.Actor, valid = a.Value, true
+			case a.Name.Local == "role" && soap12:
+				b.Role, valid = a.Value, true
+			case a.Name.Local == "relay" && soap12:
+				b.Relay, valid = headerFlag(a.Value)
+			default:
+				valid = true
+			}
+			if !valid {
+				return nil, fmt.Errorf("header block %q has the invalid %s value %q", formatName(n.Name), a.Name.Local, a.Value)
+			}
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, nil
+}
+
+// headerFlag parses the value of a boolean SOAP header attribute. SOAP 1.1 only allows 0 and 1, but
+// true and false are accepted in both versions because many SOAP 1.1 services send them.
+// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383500 and https://www.w3.org/TR/soap12-part1/#soapmu
+func headerFlag(value string) (flag bool, valid bool) {
+	switch strings.TrimSpace(value) {
+	case "1", "true":
+		return true, true
+	case "0", "false":
+		return false, true
+	}
+	return false, false
+}
+
+// HeaderBlock returns the first header block with the name, nil if the response has none.
+// The namespace is only compared if name has one.
+func (r *Response) HeaderBlock(name xml.Name) (*HeaderBlock, error) {
+	blocks, err := r.HeaderBlocks()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range blocks {
+		if b.Name.Local == name.Local && (name.Space == "" || b.Name.Space == name.Space) {
+			return b, nil
+		}
+	}
+	return nil, nil
+}
+
+// UnmarshalHeaderBlock decodes the first header block with the name into v
+func (r *Response) UnmarshalHeaderBlock(name xml.Name, v any) error {
+	b, err := r.HeaderBlock(name)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return fmt.Errorf("the response has no header block %q", name.Local)
+	}
+	return b.Unmarshal(v)
+}
+
+// processHeaders passes the header blocks targeting the client to their processors.
+// No block is processed if a mandatory block has no processor.
+func (c *Client) processHeaders(ctx context.Context, res *Response) error {
+	if len(c.config.HeaderProcessors) == 0 && c.config.IgnoreMustUnderstand {
+		return nil
+	}
+	blocks, err := res.HeaderBlocks()
+	if err != nil {
+		return err
+	}
+
+	var targeted []*HeaderBlock
+	var notUnderstood []xml.Name
+	for _, b := range blocks {
+		if !b.targets(c.config.Roles) {
+			continue
+		}
+		if _, ok := c.config.HeaderProcessors[b.Name]; ok {
+			targeted = append(targeted, b)
+		} else if b.MustUnderstand && !c.config.IgnoreMustUnderstand {
+			notUnderstood = append(notUnderstood, b.Name)
+		}
+	}
+	if len(notUnderstood) > 0 {
+		return &MustUnderstandError{Headers: notUnderstood}
+	}
+
+	for _, b := range targeted {
+		if err := c.config.HeaderProcessors[b.Name](ctx, b); err != nil {
+			return fmt.Errorf("error processing the header block %q: %w", formatName(b.Name), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package gosoap
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const wsaNS = "http://www.w3.org/2005/08/addressing"
+
+func TestHeaderBlocks(t *testing.T) {
+	t.Parallel()
+	res := &Response{
+		HeaderEntries: []byte(`
+    <wsa:MessageID>urn:uuid:1</wsa:MessageID>
+    <wsa:RelatesTo soap:mustUnderstand="1" soap:actor="http://example.com/client">urn:uuid:0</wsa:RelatesTo>
+    <Quota xmlns="urn:quota" soap:actor="http://schemas.xmlsoap.org/soap/actor/next" unit="calls">
+      <Remaining>10</Remaining>
+    </Quota>`),
+		headerNamespaces: namespaces{
+			{Name: xml.Name{Space: "xmlns", Local: "soap"}, Value: soap11EnvelopeNS},
+			{Name: xml.Name{Space: "xmlns", Local: "wsa"}, Value: wsaNS},
+		},
+		envelopeNS: soap11EnvelopeNS,
+	}
+
+	blocks, err := res.HeaderBlocks()
+	require.NoError(t, err)
+	require.Len(t, blocks, 3)
+
+	assert.Equal(t, xml.Name{Space: wsaNS, Local: "MessageID"}, blocks[0].Name)
+	assert.False(t, blocks[0].MustUnderstand)
+	assert.Equal(t, `<MessageID xmlns="http://www.w3.org/2005/08/addressing">urn:uuid:1</MessageID>`, string(blocks[0].XML))
+
+	assert.True(t, blocks[1].MustUnderstand)
+	assert.Equal(t, "http://example.com/client", blocks[1].Actor)
+	assert.Len(t, blocks[1].Attrs, 2)
+
+	assert.Equal(t, xml.Name{Space: "urn:quota", Local: "Quota"}, blocks[2].Name)
+	assert.Equal(t, soap11ActorNext, blocks[2].Actor)
+	assert.Contains(t, blocks[2].Attrs, xml.Attr{Name: xml.Name{Local: "unit"}, Value: "calls"})
+
+	var quota struct {
+		Unit      string `xml:"unit,attr"`
+		Remaining int    `xml:"urn:quota Remaining"`
+	}
+	require.NoError(t, res.UnmarshalHeaderBlock(xml.Name{Space: "urn:quota", Local: "Quota"}, &quota))
+	assert.Equal(t, "calls", quota.Unit)
+	assert.Equal(t, 10, quota.Remaining)
+
+	var relatesTo string
+	require.NoError(t, res.UnmarshalHeaderBlock(xml.Name{Local: "RelatesTo"}, &relatesTo))
+	assert.Equal(t, "urn:uuid:0", relatesTo)
+
+	err = res.UnmarshalHeaderBlock(xml.Name{Space: "urn:other", Local: "Quota"}, &quota)
+	assert.EqualError(t, err, `the response has no header block "Quota"`)
+
+	block, err := (&Response{}).HeaderBlock(xml.Name{Local: "Quota"})
+	require.NoError(t, err)
+	assert.Nil(t, block)
+}
+
+func TestHeaderBlockVersions(t *testing.T) {
+	t.Parallel()
+	header := []byte(`<Quota xmlns="urn:quota" soap:mustUnderstand="1" soap:actor="http://example.com/a" env:mustUnderstand="true" env:role="http://example.com/b" env:relay="true">10</Quota>`)
+	headerNamespaces := namespaces{
+		{Name: xml.Name{Space: "xmlns", Local: "soap"}, Value: soap11EnvelopeNS},
+		{Name: xml.Name{Space: "xmlns", Local: "env"}, Value: soap12EnvelopeNS},
+	}
+
+	blocks, err := (&Response{HeaderEntries: header, headerNamespaces: headerNamespaces, envelopeNS: soap11EnvelopeNS}).HeaderBlocks()
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.True(t, blocks[0].MustUnderstand)
+	assert.Equal(t, "http://example.com/a", blocks[0].Actor)
+	assert.Empty(t, blocks[0].Role, "SOAP 1.2 attributes are ignored in a SOAP 1.1 envelope")
+	assert.False(t, blocks[0].Relay)
+	assert.Len(t, blocks[0].Attrs, 5)
+
+	blocks, err = (&Response{HeaderEntries: header, headerNamespaces: headerNamespaces, envelopeNS: soap12EnvelopeNS}).HeaderBlocks()
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.True(t, blocks[0].MustUnderstand)
+	assert.Empty(t, blocks[0].Actor, "SOAP 1.1 attributes are ignored in a SOAP 1.2 envelope")
+	assert.Equal(t, "http://example.com/b", blocks[0].Role)
+	assert.True(t, blocks[0].Relay)
+
+	header = []byte(`<Quota xmlns="urn:quota" soap:mustUnderstand="true">10</Quota>`)
+	blocks, err = (&Response{HeaderEntries: header, headerNamespaces: headerNamespaces, envelopeNS: soap11EnvelopeNS}).HeaderBlocks()
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.True(t, blocks[0].MustUnderstand, "SOAP 1.2 values are accepted in a SOAP 1.1 envelope")
+
+	header = []byte(`<Quota xmlns="urn:quota" soap:mustUnderstand="yes">10</Quota>`)
+	_, err = (&Response{HeaderEntries: header, headerNamespaces: headerNamespaces, envelopeNS: soap11EnvelopeNS}).HeaderBlocks()
+	assert.EqualError(t, err, `header block "{urn:quota}Quota" has the invalid mustUnderstand value "yes"`)
+	_, err = (&Response{HeaderEntries: header, headerNamespaces: headerNamespaces}).HeaderBlocks()
+	assert.Error(t, err, "SOAP 1.1 is assumed without an envelope")
+}
+
+func TestMustUnderstand(t *testing.T) {
+	t.Parallel()
+	var header, envelopeNS string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `<soap:Envelope xmlns:soap="`+envelopeNS+`">
+  <soap:Header>`+header+`</soap:Header>
+  <soap:Body><GetOrderResponse xmlns="http://example.com/order"><OrderID>1</OrderID></GetOrderResponse></soap:Body>
+</soap:Envelope>`)
+	}))
+	t.Cleanup(server.Close)
+
+	session := xml.Name{Space: "urn:session", Local: "Session"}
+	var processed []string
+	config := Config{
+		Roles: []string{"http://example.com/client"},
+		HeaderProcessors: map[xml.Name]HeaderProcessor{
+			session: func(ctx context.Context, block *HeaderBlock) error {
+				var id string
+				if err := block.Unmarshal(&id); err != nil {
+					return err
+				}
+				if id == "expired" {
+					return errors.New("session expired")
+				}
+				processed = append(processed, id)
+				return nil
+			},
+		},
+	}
+	client := newTestClient(t, "./testdata/order.wsdl", server, config)
+	ignoring := newTestClient(t, "./testdata/order.wsdl", server, Config{IgnoreMustUnderstand: true})
+
+	testCases := []struct {
+		description string
+		soap12      bool
+		header      string
+		processed   []string
+		expectedErr string
+	}{
+		{
+			description: "processed",
+			header:      `<s:Session xmlns:s="urn:session" soap:mustUnderstand="1">abc</s:Session><s:Session xmlns:s="urn:session">def</s:Session>`,
+			processed:   []string{"abc", "def"},
+		},
+		{
+			description: "optional without processor",
+			header:      `<Quota xmlns="urn:quota">10</Quota>`,
+		},
+		{
+			description: "mandatory without processor",
+			header:      `<Quota xmlns="urn:quota" soap:mustUnderstand="1">10</Quota><s:Session xmlns:s="urn:session">abc</s:Session>`,
+			expectedErr: `invalid response header for operation "GetOrder": the response contains mandatory header blocks which are not understood: {urn:quota}Quota`,
+		},
+		{
+			description: "SOAP 1.1 next actor",
+			header:      `<Quota xmlns="urn:quota" soap:mustUnderstand="1" soap:actor="http://schemas.xmlsoap.org/soap/actor/next">10</Quota>`,
+			expectedErr: `invalid response header for operation "GetOrder": the response contains mandatory header blocks which are not understood: {urn:quota}Quota`,
+		},
+		{
+			description: "SOAP 1.2 role of the client",
+			soap12:      true,
+			header:      `<Quota xmlns="urn:quota" soap:mustUnderstand="true" soap:role="http://example.com/client">10</Quota>`,
+			expectedErr: `invalid response header for operation "GetOrder": the response contains mandatory header blocks which are not understood: {urn:quota}Quota`,
+		},
+		{
+			description: "SOAP 1.2 attributes in a SOAP 1.1 envelope",
+			header:      `<Quota xmlns="urn:quota" xmlns:env="http://www.w3.org/2003/05/soap-envelope" env:mustUnderstand="true">10</Quota>`,
+		},
+		{
+			description: "SOAP 1.2 value in a SOAP 1.1 envelope",
+			header:      `<Quota xmlns="urn:quota" soap:mustUnderstand="true">10</Quota>`,
+			expectedErr: `invalid response header for operation "GetOrder": the response contains mandatory header blocks which are not understood: {urn:quota}Quota`,
+		},
+		{
+			description: "processed SOAP 1.2 value in a SOAP 1.1 envelope",
+			header:      `<s:Session xmlns:s="urn:session" soap:mustUnderstand="true">abc</s:Session>`,
+			processed:   []string{"abc"},
+		},
+		{
+			description: "other actor",
+			header:      `<Quota xmlns="urn:quota" soap:mustUnderstand="1" soap:actor="http://example.com/proxy">10</Quota>`,
+		},
+		{
+			description: "SOAP 1.2 none role",
+			soap12:      true,
+			header:      `<Quota xmlns="urn:quota" soap:mustUnderstand="true" soap:role="http://www.w3.org/2003/05/soap-envelope/role/none">10</Quota>`,
+		},
+		{
+			description: "processor error",
+			header:      `<s:Session xmlns:s="urn:session">expired</s:Session>`,
+			expectedErr: `invalid response header for operation "GetOrder": error processing the header block "{urn:session}Session": session expired`,
+		},
+	}
+	for _, tc := range testCases {
+		header = tc.header
+		envelopeNS = soap11EnvelopeNS
+		if tc.soap12 {
+			envelopeNS = soap12EnvelopeNS
+		}
+		processed = nil
+		res, err := client.Call(context.Background(), "GetOrder", Params{"OrderID": 1})
+		if tc.expectedErr != "" {
+			assert.EqualError(t, err, tc.expectedErr, tc.description)
+			assert.NotNil(t, res, tc.description)
+		} else {
+			assert.NoError(t, err, tc.description)
+		}
+		assert.Equal(t, tc.processed, processed, tc.description)
+
+		_, err = ignoring.Call(context.Background(), "GetOrder", Params{"OrderID": 1})
+		assert.NoError(t, err, tc.description)
+	}
+
+	envelopeNS = soap11EnvelopeNS
+	header = `<Quota xmlns="urn:quota" soap:mustUnderstand="1">10</Quota>`
+	_, err := client.Call(context.Background(), "GetOrder", Params{"OrderID": 1})
+	var mustUnderstand *MustUnderstandError
+	require.ErrorAs(t, err, &mustUnderstand)
+	assert.Equal(t, []xml.Name{{Space: "urn:quota", Local: "Quota"}}, mustUnderstand.Headers)
+}
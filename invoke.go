@@ -0,0 +1,238 @@
+package gosoap
+
+import (
+	"context"
+	"encoding"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// InvokeOption configures a call made by Invoke, every CallOption is an InvokeOption
+type InvokeOption interface {
+	applyInvoke(inv *invocation)
+}
+
+type invokeOptionFunc func(inv *invocation)
+
+func (f invokeOptionFunc) applyInvoke(inv *invocation) {
+	f(inv)
+}
+
+// invocation is a call made by Invoke
+type invocation struct {
+	request *Request
+	// headers are the values the response header blocks are decoded into
+	headers []any
+}
+
+// WithResponseHeader decodes the response header block named like the element of v into v.
+// v has to be a pointer to a struct with an XMLName, the namespace of the block is only compared
+// if the XMLName has one. v is left unchanged if the response has no such header block.
+func WithResponseHeader(v any) InvokeOption {
+	return invokeOptionFunc(func(inv *invocation) {
+		inv.headers = append(inv.headers, v)
+	})
+}
+
+// Invoke calls an operation with req as the body and decodes the output into a Resp, a fault is returned as FaultError.
+// If the output is a wrapper like GetPriceResponse and Resp doesn't describe the wrapper, because it is a simple type,
+// a slice or a struct with a different XMLName, the children of the wrapper are decoded instead,
+// e.g. Invoke[GetPrice, float64] decodes GetPriceResponse/Price. A simple type is decoded from the first element the
+// WSDL declares for the wrapper, or the first part of an rpc output, an error is returned if the wrapper has no such child.
+func Invoke[Req, Resp any](ctx context.Context, c *Client, operation string, req Req, opts ...InvokeOption) (Resp, error) {
+	var out Resp
+	inv := &invocation{request: NewRequest(operation, req)}
+	for _, opt := range opts {
+		opt.applyInvoke(inv)
+	}
+
+	res, err := c.Do(ctx, inv.request)
+	if err != nil {
+		return out, err
+	}
+	if err := res.fault(); err != nil {
+		return out, err
+	}
+	if err := res.decodeOutput(&out); err != nil {
+		return out, fmt.Errorf("error decoding the output of operation %q: %w", operation, err)
+	}
+	for _, h := range inv.headers {
+		if err := res.decodeHeaderBlock(h); err != nil {
+			return out, fmt.Errorf("error decoding the header of operation %q: %w", operation, err)
+		}
+	}
+	return out, nil
+}
+
+// decodeOutput decodes the body element into v, or its children if v doesn't describe a wrapper element.
+// v is a pointer.
+func (r *Response) decodeOutput(v any) error {
+	nodes, err := fragmentElements(r.Body, r.bodyNamespaces, true)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return errors.New("body is empty")
+	}
+	root := nodes[0]
+	t := reflect.TypeOf(v).Elem()
+	element, named := typeElementName(t)
+	if !r.unwrap(root.Name, t, element, named) {
+		return decodeNode(root, v, r.types)
+	}
+	if !named && isSimpleType(t) {
+		element, named = r.outputElement(root.Name)
+	}
+
+	decoded := false
+	for _, c := range root.elements() {
+		if named && c.Name.Local != element.Local {
+			continue
+		}
+		if err := decodeNode(c, v, r.types); err != nil {
+			return err
+		}
+		decoded = true
+		if t.Kind() != reflect.Slice || isSimpleType(t) {
+			break
+		}
+	}
+	switch {
+	case decoded || (t.Kind() == reflect.Slice && !isSimpleType(t)):
+		// a list can be empty
+		return nil
+	case named:
+		return fmt.Errorf("%q has no child element %q", root.Name.Local, element.Local)
+	}
+	return fmt.Errorf("%q has no child elements", root.Name.Local)
+}
+
+// unwrap reports whether a value of type t is decoded from the children of the body element name,
+// element is the element name of t if it has one
+func (r *Response) unwrap(name xml.Name, t reflect.Type, element xml.Name, named bool) bool {
+	rpc := r.wsdl != nil && r.wsdl.binding != nil && r.wsdl.binding.style(r.operation) == "rpc"
+	if !rpc && !r.wrapper(name) {
+		return false
+	}
+	if named {
+		return element.Local != name.Local
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() != reflect.Struct || isSimpleType(t)
+}
+
+// wrapper reports whether the document style body element name wraps the output values, i.e. it is the element
+// of the output message and its type declares child elements. Without a WSDL elements named like "…Response" are wrappers.
+func (r *Response) wrapper(name xml.Name) bool {
+	if r.wsdl == nil || r.wsdl.definitions == nil || r.wsdl.binding == nil || r.operation == nil {
+		return strings.HasSuffix(name.Local, "Response")
+	}
+	msg, err := r.wsdl.definitions.outputMessage(r.wsdl.binding, r.operation)
+	if err != nil {
+		return false
+	}
+	body := outputBody(r.operation)
+	for _, p := range msg.Parts {
+		if body.includes(p.Name) && p.ElementName == name {
+			_, ok := r.outputElement(name)
+			return ok
+		}
+	}
+	return false
+}
+
+// outputElement returns the name of the child of the body element wrapper a simple value is decoded from,
+// the first part of an rpc output or the first element the schema declares for the wrapper.
+// It returns false if the WSDL doesn't describe the wrapper.
+func (r *Response) outputElement(wrapper xml.Name) (xml.Name, bool) {
+	if r.wsdl == nil || r.wsdl.definitions == nil || r.wsdl.binding == nil {
+		return xml.Name{}, false
+	}
+	d := r.wsdl.definitions
+	if r.wsdl.binding.style(r.operation) == "rpc" {
+		msg, err := d.outputMessage(r.wsdl.binding, r.operation)
+		if err != nil {
+			return xml.Name{}, false
+		}
+		body := outputBody(r.operation)
+		for _, p := range msg.Parts {
+			if body.includes(p.Name) {
+				return xml.Name{Local: p.Name}, true
+			}
+		}
+		return xml.Name{}, false
+	}
+	decl := d.schemas.element(wrapper)
+	if decl == nil {
+		return xml.Name{}, false
+	}
+	ct, _, _ := d.schemas.elementType(decl)
+	if ct == nil {
+		return xml.Name{}, false
+	}
+	if e := d.schemas.firstElement(d.schemas.contentModel(ct), 0); e != nil {
+		return e.QName, true
+	}
+	return xml.Name{}, false
+}
+
+// typeElementName returns the element name of values of type t, or of its items if t is a slice
+func typeElementName(t reflect.Type) (xml.Name, bool) {
+	for t.Kind() == reflect.Pointer || (t.Kind() == reflect.Slice && t != byteSliceType) {
+		t = t.Elem()
+	}
+	return elementName(reflect.New(t).Interface())
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isSimpleType reports whether values of type t are decoded from character data instead of child elements
+func isSimpleType(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == byteSliceType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	return t.Kind() != reflect.Struct && t.Kind() != reflect.Slice
+}
+
+// decodeHeaderBlock decodes the response header block with the element name of v into v
+func (r *Response) decodeHeaderBlock(v any) error {
+	name, ok := elementName(v)
+	if !ok {
+		return fmt.Errorf("the header block %T has no element name", v)
+	}
+	b, err := r.HeaderBlock(name)
+	if err != nil || b == nil {
+		return err
+	}
+	return b.Unmarshal(v)
+}
+
+// fragmentElements parses the content of the body or header into namespace resolved elements
+func fragmentElements(content []byte, inherited namespaces, references bool) ([]*xmlNode, error) {
+	prepared, err := prepareBody(content, inherited, references)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := parseXMLFragment(prepared, inherited)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the content: %w", err)
+	}
+	return nodes, nil
+}
+
+// decodeNode unmarshals a single element into v, Polymorphic values are resolved with types
+func decodeNode(n *xmlNode, v any, types *TypeRegistry) error {
+	data, err := marshalXMLNodes([]*xmlNode{n})
+	if err != nil {
+		return err
+	}
+	return unmarshal(data, v, types)
+}
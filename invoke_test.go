@@ -0,0 +1,182 @@
+package gosoap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type getOrder struct {
+	XMLName xml.Name `xml:"http://example.com/order GetOrder"`
+	OrderID int64    `xml:"OrderID"`
+}
+
+type getOrderResponse struct {
+	XMLName xml.Name `xml:"http://example.com/order GetOrderResponse"`
+	OrderID int64    `xml:"OrderID"`
+	Items   []struct {
+		SKU      string `xml:"sku,attr"`
+		Quantity int    `xml:"Quantity"`
+	} `xml:"Item"`
+}
+
+type session struct {
+	XMLName xml.Name `xml:"urn:session Session"`
+	ID      string   `xml:"ID"`
+}
+
+func TestInvoke(t *testing.T) {
+	t.Parallel()
+	var reqBody []byte
+	response := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:s="urn:session">
+  <soap:Header>
+    <s:Other>ignored</s:Other>
+    <s:Session><s:ID>abc</s:ID></s:Session>
+  </soap:Header>
+  <soap:Body>
+    <GetOrderResponse xmlns="http://example.com/order">
+      <OrderID>42</OrderID>
+      <Item sku="a"><Quantity>2</Quantity></Item>
+      <Item sku="b"><Quantity>1</Quantity></Item>
+    </GetOrderResponse>
+  </soap:Body>
+</soap:Envelope>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		reqBody, err = io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		_, _ = io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	client := newTestClient(t, "./testdata/order.wsdl", server, Config{})
+	ctx := context.Background()
+
+	t.Run("output element", func(t *testing.T) {
+		var s session
+		res, err := Invoke[getOrder, getOrderResponse](ctx, client, "GetOrder", getOrder{OrderID: 42},
+			WithHeaderEntries(HeaderEntry{Name: "Token", Namespace: "urn:session", Content: "secret"}),
+			WithResponseHeader(&s),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), res.OrderID)
+		require.Len(t, res.Items, 2)
+		assert.Equal(t, "b", res.Items[1].SKU)
+		assert.Equal(t, 2, res.Items[0].Quantity)
+		assert.Equal(t, "abc", s.ID)
+		assert.Contains(t, string(reqBody), `<Token xmlns="urn:session">secret</Token>`)
+		assert.Contains(t, string(reqBody), `<GetOrder xmlns="http://example.com/order">
+            <OrderID>42</OrderID>
+        </GetOrder>`)
+	})
+
+	t.Run("unnamed struct", func(t *testing.T) {
+		res, err := Invoke[Params, struct {
+			OrderID int64 `xml:"OrderID"`
+		}](ctx, client, "GetOrder", Params{"OrderID": 42})
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), res.OrderID)
+	})
+
+	t.Run("unwrapped", func(t *testing.T) {
+		id, err := Invoke[Params, int64](ctx, client, "GetOrder", Params{"OrderID": 42})
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), id)
+	})
+
+	t.Run("child element", func(t *testing.T) {
+		type item struct {
+			XMLName xml.Name `xml:"Item"`
+			SKU     string   `xml:"sku,attr"`
+		}
+		items, err := Invoke[Params, []item](ctx, client, "GetOrder", Params{"OrderID": 42})
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		assert.Equal(t, "a", items[0].SKU)
+		assert.Equal(t, "b", items[1].SKU)
+	})
+
+	t.Run("header without name", func(t *testing.T) {
+		var header struct{ ID string }
+		_, err := Invoke[Params, int64](ctx, client, "GetOrder", Params{"OrderID": 42}, WithResponseHeader(&header))
+		assert.EqualError(t, err, `error decoding the header of operation "GetOrder": the header block *struct { ID string } has no element name`)
+	})
+}
+
+func TestInvokeOutputElement(t *testing.T) {
+	t.Parallel()
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>`+response+`</soap:Body></soap:Envelope>`)
+	}))
+	t.Cleanup(server.Close)
+	client := newTestClient(t, "./testdata/order.wsdl", server, Config{})
+	ctx := context.Background()
+
+	response = `<GetOrderResponse xmlns="http://example.com/order"><Note>late</Note><OrderID>7</OrderID></GetOrderResponse>`
+	id, err := Invoke[Params, int64](ctx, client, "GetOrder", Params{"OrderID": 7})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), id, "a simple value is decoded from the first element of the wrapper")
+
+	response = `<GetOrderResponse xmlns="http://example.com/order"><Other>1</Other></GetOrderResponse>`
+	_, err = Invoke[Params, int64](ctx, client, "GetOrder", Params{"OrderID": 7})
+	assert.EqualError(t, err, `error decoding the output of operation "GetOrder": "GetOrderResponse" has no child element "OrderID"`)
+	_, err = Invoke[Params, getOrder](ctx, client, "GetOrder", Params{"OrderID": 7})
+	assert.EqualError(t, err, `error decoding the output of operation "GetOrder": "GetOrderResponse" has no child element "GetOrder"`)
+	type item struct {
+		XMLName xml.Name `xml:"Item"`
+	}
+	items, err := Invoke[Params, []item](ctx, client, "GetOrder", Params{"OrderID": 7})
+	require.NoError(t, err)
+	assert.Empty(t, items, "a list can be empty")
+
+	response = `<GetOrderResponse xmlns="http://example.com/order"/>`
+	_, err = Invoke[Params, int64](ctx, client, "GetOrder", Params{"OrderID": 7})
+	assert.EqualError(t, err, `error decoding the output of operation "GetOrder": "GetOrderResponse" has no child element "OrderID"`)
+
+	rpc := newTestClient(t, "./testdata/rpc.wsdl", server, Config{})
+	response = `<ns:findItemsResponse xmlns:ns="urn:inventory"><count>2</count><findItemsReturn>a b</findItemsReturn></ns:findItemsResponse>`
+	found, err := Invoke[Params, string](ctx, rpc, "findItems", Params{"category": "tools", "limit": 2, "tags": []string{}})
+	require.NoError(t, err)
+	assert.Equal(t, "a b", found, "a simple value is decoded from the first part of an rpc output")
+
+	spec, err := os.ReadFile("./testdata/order.wsdl")
+	require.NoError(t, err)
+	spec = bytes.ReplaceAll(spec, []byte("GetOrderResponse"), []byte("GetOrderResult"))
+	result, err := NewClient(SourceFromBytes(spec), &Config{Client: server.Client()})
+	require.NoError(t, err)
+	setAddress(result, server.URL)
+	response = `<GetOrderResult xmlns="http://example.com/order"><Note>late</Note><OrderID>7</OrderID></GetOrderResult>`
+	id, err = Invoke[Params, int64](ctx, result, "GetOrder", Params{"OrderID": 7})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), id, "the output element is unwrapped whatever its name")
+}
+
+func TestInvokeFault(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <soap:Fault>
+      <faultcode>soap:Server</faultcode>
+      <faultstring>order not found</faultstring>
+    </soap:Fault>
+  </soap:Body>
+</soap:Envelope>`)
+	}))
+	t.Cleanup(server.Close)
+	client := newTestClient(t, "./testdata/order.wsdl", server, Config{})
+
+	_, err := Invoke[Params, getOrderResponse](context.Background(), client, "GetOrder", Params{"OrderID": 1})
+	var fault FaultError
+	require.ErrorAs(t, err, &fault)
+	assert.Equal(t, "order not found", fault.Fault.Description)
+}
@@ -0,0 +1,678 @@
+package gosoap
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSON mapping
+//
+// RequestFromJSON and Response.JSON convert between SOAP bodies and JSON using the schema of the WSDL:
+//
+//   - The body is an object with a member per body element, named after the element.
+//     For rpc style operations the body holds the wrapper element, the members of the wrapper are the message parts.
+//   - Elements are named by their local name. Elements the schema doesn't declare and whose namespace differs
+//     from the namespace of their parent are named "{namespace}local".
+//   - Elements with simple content and without attributes are scalars: numbers for the numeric types,
+//     booleans for xs:boolean and strings for everything else, using the lexical form of the value.
+//     List types are arrays and nil elements are null.
+//   - Elements with attributes or child elements are objects. Attributes are members named "@local",
+//     attributes the schema doesn't declare which have a namespace are named "@{namespace}local", like xml:lang.
+//     Simple content next to attributes is the member "#text".
+//   - Elements declared with maxOccurs > 1 are arrays, even if they occur once. Undeclared elements
+//     are arrays if they occur more than once.
+//   - Mixed content is the member "#mixed", an array of strings and objects with a single element member in document order.
+//
+// When a request is encoded, child elements are written in the order of the content model of their type,
+// repetitions of a group are written element by element because JSON doesn't keep their interleaving.
+
+// RequestFromJSON converts a JSON body to a request for an operation, see the JSON mapping above.
+// opts are applied to the request before the operation is looked up, WithOperationNames selects
+// one of several operations with the same name.
+// Operations using the SOAP encoding are not supported.
+func (c *Client) RequestFromJSON(operation string, data []byte, opts ...CallOption) (*Request, error) {
+	s := c.state()
+	if s.definitions == nil {
+		return nil, errors.New("converting JSON requires a WSDL")
+	}
+	req := NewRequest(operation, nil)
+	for _, opt := range opts {
+		opt(req)
+	}
+	op, err := s.binding.operation(operation, req.InputName, req.OutputName)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := s.definitions.inputMessage(s.binding, op)
+	if err != nil {
+		return nil, err
+	}
+	body := inputBody(op)
+	if body != nil && body.Use == "encoded" {
+		return nil, fmt.Errorf("operation %q uses the SOAP encoding, it can't be converted from JSON", operation)
+	}
+
+	value, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	members, ok := value.(jsonObject)
+	if !ok {
+		return nil, errors.New("JSON body must be an object")
+	}
+	b := &jsonBuilder{decoder: &decoder{validator: &validator{set: s.definitions.schemas, patterns: map[string]compiledPattern{}}}}
+
+	if s.binding.style(op) == "rpc" {
+		if len(members) != 1 {
+			return nil, errors.New("JSON body of an rpc operation must have a single member for the wrapper element")
+		}
+		accessors, ok := members[0].value.(jsonObject)
+		if !ok {
+			return nil, fmt.Errorf("wrapper %q must be an object", members[0].key)
+		}
+		params := Params{}
+		for _, part := range msg.Parts {
+			if !body.includes(part.Name) {
+				continue
+			}
+			v, ok := accessors.get(part.Name)
+			if !ok {
+				return nil, fmt.Errorf("JSON body has no value for message part %q", part.Name)
+			}
+			decl := &xsdElement{QName: xml.Name{Local: part.Name}, TypeName: part.TypeName}
+			if part.Element != "" {
+				decl = s.definitions.schemas.element(part.ElementName)
+			}
+			n, err := b.element(xml.Name{Local: part.Name}, decl, v, "/"+members[0].key+"/"+part.Name)
+			if err != nil {
+				return nil, err
+			}
+			params[part.Name] = nodeContent(n)
+		}
+		req.Body = params
+		return req, nil
+	}
+
+	var parts []*wsdlMessagePart
+	for _, part := range msg.Parts {
+		if !body.includes(part.Name) {
+			continue
+		}
+		if part.Element == "" {
+			return nil, fmt.Errorf("message part %q of a document style operation has no element", part.Name)
+		}
+		parts = append(parts, part)
+	}
+	params := Params{}
+	var single any
+	for _, m := range members {
+		var part *wsdlMessagePart
+		for _, p := range parts {
+			if jsonKey(m.key, p.ElementName) {
+				part = p
+			}
+		}
+		if part == nil {
+			return nil, fmt.Errorf("unknown body element %q", m.key)
+		}
+		n, err := b.element(part.ElementName, s.definitions.schemas.element(part.ElementName), m.value, "/"+part.ElementName.Local)
+		if err != nil {
+			return nil, err
+		}
+		params[part.Name] = nodeContent(n)
+		single = xmlElement{node: n}
+	}
+	for _, p := range parts {
+		if _, ok := params[p.Name]; !ok {
+			return nil, fmt.Errorf("JSON body has no element %q", p.ElementName.Local)
+		}
+	}
+	req.Body = params
+	if len(parts) == 1 {
+		req.Body = single
+	}
+	return req, nil
+}
+
+// JSON converts the body of the response to JSON, see the JSON mapping above
+func (r *Response) JSON() ([]byte, error) {
+	root, err := r.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(root.jsonMembers(""))
+}
+
+// jsonValue converts a node to its JSON representation
+func (n *Node) jsonValue() any {
+	if n.Nil {
+		return nil
+	}
+	if len(n.Attrs) == 0 && len(n.Children) == 0 {
+		return jsonScalar(n.Value, n.Text)
+	}
+	var members jsonObject
+	names := make([]string, 0, len(n.Attrs))
+	for name := range n.Attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		members = append(members, jsonMember{"@" + name, jsonScalar(n.Attrs[name], n.attrText[name])})
+	}
+	switch {
+	case len(n.content) > 0:
+		mixed := make([]any, 0, len(n.content))
+		for _, c := range n.content {
+			if child, ok := c.(*Node); ok {
+				mixed = append(mixed, jsonObject{{child.jsonKey(n.Name.Space), child.jsonValue()}})
+			} else {
+				mixed = append(mixed, c)
+			}
+		}
+		members = append(members, jsonMember{"#mixed", mixed})
+	case len(n.Children) == 0:
+		members = append(members, jsonMember{"#text", jsonScalar(n.Value, n.Text)})
+	default:
+		members = append(members, n.jsonMembers(n.Name.Space)...)
+	}
+	return members
+}
+
+// jsonMembers groups the children of a node by name, repeated elements become arrays
+func (n *Node) jsonMembers(space string) jsonObject {
+	members := jsonObject{}
+	index := map[string]int{}
+	lists := map[string]bool{}
+	for _, c := range n.Children {
+		key := c.jsonKey(space)
+		i, seen := index[key]
+		switch {
+		case !seen && c.Repeated:
+			index[key], lists[key] = len(members), true
+			members = append(members, jsonMember{key, []any{c.jsonValue()}})
+		case !seen:
+			index[key] = len(members)
+			members = append(members, jsonMember{key, c.jsonValue()})
+		case lists[key]:
+			members[i].value = append(members[i].value.([]any), c.jsonValue())
+		default:
+			lists[key] = true
+			members[i].value = []any{members[i].value, c.jsonValue()}
+		}
+	}
+	return members
+}
+
+// jsonKey names a child node, undeclared elements in a foreign namespace are qualified
+func (n *Node) jsonKey(parentSpace string) string {
+	if n.declared || n.Name.Space == "" || n.Name.Space == parentSpace {
+		return n.Name.Local
+	}
+	return "{" + n.Name.Space + "}" + n.Name.Local
+}
+
+// jsonScalar converts a simple value, text is its lexical form
+func jsonScalar(v any, text string) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool, int64:
+		return v
+	case *big.Int:
+		return jsonNumber(text, v.String())
+	case *big.Rat:
+		return jsonNumber(text, decimalString(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return collapseWhitespace(text)
+		}
+		return jsonNumber(text, strconv.FormatFloat(v, 'g', -1, 64))
+	case []any:
+		fields := strings.Fields(text)
+		items := make([]any, 0, len(v))
+		for i, item := range v {
+			field := ""
+			if i < len(fields) {
+				field = fields[i]
+			}
+			items = append(items, jsonScalar(item, field))
+		}
+		return items
+	case string:
+		return v
+	}
+	return collapseWhitespace(text)
+}
+
+// jsonNumber keeps the lexical form of a number so no digits are lost, forms JSON doesn't allow
+// like "+1" or ".5" are replaced by formatted
+func jsonNumber(text, formatted string) json.Number {
+	text = collapseWhitespace(text)
+	if text != "" && (text[0] == '-' || text[0] >= '0' && text[0] <= '9') && json.Valid([]byte(text)) {
+		return json.Number(text)
+	}
+	return json.Number(formatted)
+}
+
+// decimalString formats a decimal without exponent and without losing digits
+func decimalString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	for prec := 1; ; prec++ {
+		s := r.FloatString(prec)
+		if parsed, ok := new(big.Rat).SetString(s); ok && parsed.Cmp(r) == 0 {
+			return s
+		}
+	}
+}
+
+// jsonMember is a member of a JSON object, objects keep the order of their members
+type jsonMember struct {
+	key   string
+	value any
+}
+
+type jsonObject []jsonMember
+
+func (o jsonObject) get(key string) (any, bool) {
+	for _, m := range o {
+		if m.key == key {
+			return m.value, true
+		}
+	}
+	return nil, false
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// decodeJSON decodes a JSON document keeping the order of object members, numbers are decoded as json.Number
+func decodeJSON(data []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	v, err := decodeJSONValue(d)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+func decodeJSONValue(d *json.Decoder) (any, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		o := jsonObject{}
+		for d.More() {
+			key, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeJSONValue(d)
+			if err != nil {
+				return nil, err
+			}
+			o = append(o, jsonMember{key.(string), v})
+		}
+		_, err := d.Token()
+		return o, err
+	case json.Delim('['):
+		a := []any{}
+		for d.More() {
+			v, err := decodeJSONValue(d)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		_, err := d.Token()
+		return a, err
+	}
+	return t, nil
+}
+
+// jsonKey reports whether a member name refers to an element, "{namespace}local" names have to match the namespace
+func jsonKey(key string, name xml.Name) bool {
+	if strings.HasPrefix(key, "{") {
+		return key == "{"+name.Space+"}"+name.Local
+	}
+	return key == name.Local
+}
+
+// parseJSONKey returns the element name of a member, unqualified names get the namespace of the parent
+func parseJSONKey(key, parentSpace string) xml.Name {
+	if space, local, ok := strings.Cut(strings.TrimPrefix(key, "{"), "}"); ok && strings.HasPrefix(key, "{") {
+		return xml.Name{Space: space, Local: local}
+	}
+	return xml.Name{Space: parentSpace, Local: key}
+}
+
+// jsonBuilder converts JSON values to XML trees, the decoder provides the schema lookups
+type jsonBuilder struct {
+	*decoder
+}
+
+// element converts the JSON value of an element, decl is nil for elements the schema doesn't declare
+func (b *jsonBuilder) element(name xml.Name, decl *xsdElement, value any, path string) (*xmlNode, error) {
+	n := &xmlNode{Name: name}
+	if value == nil {
+		n.Attr = append(n.Attr,
+			xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNS},
+			xml.Attr{Name: xml.Name{Local: "xsi:nil"}, Value: "true"},
+		)
+		return n, nil
+	}
+	var ct *xsdComplexType
+	var st *xsdSimpleType
+	if decl != nil {
+		decl = b.set.resolveElement(decl)
+		ct, st, _ = b.set.elementType(decl)
+	}
+
+	members, ok := value.(jsonObject)
+	if !ok {
+		if ct != nil && ct.SimpleContent == nil {
+			return nil, fmt.Errorf("%s: element with complex content must be an object", path)
+		}
+		text, err := jsonText(value, path)
+		if err != nil {
+			return nil, err
+		}
+		if _, isList := value.([]any); isList && b.primitive(st, 0) != "list" {
+			return nil, fmt.Errorf("%s: unexpected array", path)
+		}
+		n.Children = append(n.Children, &xmlNode{Text: text})
+		return n, nil
+	}
+
+	var uses []*xsdAttribute
+	if ct != nil {
+		uses = b.set.attributeUses(ct)
+	}
+	var children jsonObject
+	for _, m := range members {
+		switch {
+		case strings.HasPrefix(m.key, "@"):
+			attrName := parseJSONKey(m.key[1:], "")
+			for _, use := range uses {
+				if a := b.set.attribute(use); a.QName.Local == attrName.Local && attrName.Space == "" {
+					attrName = a.QName
+				}
+			}
+			text, err := jsonText(m.value, path+"/"+m.key)
+			if err != nil {
+				return nil, err
+			}
+			n.Attr = append(n.Attr, xml.Attr{Name: attrName, Value: text})
+		case m.key == "#text":
+			text, err := jsonText(m.value, path+"/#text")
+			if err != nil {
+				return nil, err
+			}
+			n.Children = append(n.Children, &xmlNode{Text: text})
+		case m.key == "#mixed":
+			items, ok := m.value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: #mixed must be an array", path)
+			}
+			for _, item := range items {
+				switch item := item.(type) {
+				case string:
+					n.Children = append(n.Children, &xmlNode{Text: item})
+				case jsonObject:
+					nodes, err := b.children(ct, name.Space, item, path)
+					if err != nil {
+						return nil, err
+					}
+					n.Children = append(n.Children, nodes...)
+				default:
+					return nil, fmt.Errorf("%s: #mixed items must be strings or objects", path)
+				}
+			}
+		default:
+			children = append(children, m)
+		}
+	}
+	if len(children) > 0 && decl != nil && (ct == nil || ct.SimpleContent != nil) {
+		return nil, fmt.Errorf("%s: unknown element %q", path, children[0].key)
+	}
+	if len(children) > 0 {
+		nodes, err := b.children(ct, name.Space, children, path)
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, nodes...)
+	}
+	return n, nil
+}
+
+// children converts the members of an object to child elements. Declared elements are written in the order
+// of the content model, members matching a wildcard where the wildcard is and undeclared content in member order.
+func (b *jsonBuilder) children(ct *xsdComplexType, space string, members jsonObject, path string) ([]*xmlNode, error) {
+	if ct == nil {
+		var nodes []*xmlNode
+		for _, m := range members {
+			elements, err := b.repeat(parseJSONKey(m.key, space), nil, true, m.value, path+"/"+m.key)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, elements...)
+		}
+		return nodes, nil
+	}
+	used := make([]bool, len(members))
+	var nodes []*xmlNode
+	for _, p := range b.flatten(b.set.contentModel(ct), 0) {
+		for i, m := range members {
+			if used[i] {
+				continue
+			}
+			var decl *xsdElement
+			var name xml.Name
+			switch {
+			case p.Element != nil:
+				for _, s := range b.set.substitutes(p.Element) {
+					if jsonKey(m.key, s.QName) {
+						decl, name = s, s.QName
+					}
+				}
+				if decl == nil {
+					continue
+				}
+			case p.Any != nil:
+				name = parseJSONKey(m.key, space)
+				if !wildcardAllows(p.Any, name) {
+					continue
+				}
+				decl = b.set.element(name)
+			}
+			_, repeated := b.childElement(b.set.contentModel(ct), name, false, 0)
+			if decl == nil {
+				repeated = true
+			}
+			elements, err := b.repeat(name, decl, repeated, m.value, path+"/"+m.key)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, elements...)
+			used[i] = true
+		}
+	}
+	for i, m := range members {
+		if !used[i] {
+			return nil, fmt.Errorf("%s: unknown element %q", path, m.key)
+		}
+	}
+	return nodes, nil
+}
+
+// repeat converts the value of a member to elements, arrays of repeated elements become one element per item
+func (b *jsonBuilder) repeat(name xml.Name, decl *xsdElement, repeated bool, value any, path string) ([]*xmlNode, error) {
+	items, isArray := value.([]any)
+	if !isArray || !repeated {
+		n, err := b.element(name, decl, value, path)
+		if err != nil {
+			return nil, err
+		}
+		return []*xmlNode{n}, nil
+	}
+	nodes := make([]*xmlNode, 0, len(items))
+	for _, item := range items {
+		n, err := b.element(name, decl, item, path)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// flatten returns the element and wildcard particles of a content model in document order
+func (b *jsonBuilder) flatten(groups []*xsdModelGroup, depth int) []*xsdParticle {
+	if depth > 32 {
+		return nil
+	}
+	var particles []*xsdParticle
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		for _, p := range g.Particles {
+			switch {
+			case p.Element != nil, p.Any != nil:
+				particles = append(particles, p)
+			case p.ModelGroup != nil:
+				particles = append(particles, b.flatten([]*xsdModelGroup{p.ModelGroup}, depth+1)...)
+			case p.Group != nil:
+				particles = append(particles, b.flatten([]*xsdModelGroup{b.set.group(p.Group).modelGroup()}, depth+1)...)
+			}
+		}
+	}
+	return particles
+}
+
+// jsonText returns the lexical form of a scalar, arrays of scalars are lists
+func jsonText(value any, path string) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, nested := item.([]any); nested {
+				return "", fmt.Errorf("%s: nested arrays are not supported", path)
+			}
+			text, err := jsonText(item, path)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, text)
+		}
+		return strings.Join(items, " "), nil
+	}
+	return "", fmt.Errorf("%s: expected a scalar value", path)
+}
+
+// xmlElement writes an XML tree through the request encoder, it is a NamedElement
+// so the body layout sees the name of the element
+type xmlElement struct {
+	node *xmlNode
+	// parentSpace is the namespace of the parent element, unqualified elements reset the default namespace
+	parentSpace string
+}
+
+func (e xmlElement) Name() xml.StartElement {
+	start := xml.StartElement{Name: e.node.Name, Attr: e.node.Attr}
+	switch {
+	case e.node.Name.Space == e.parentSpace:
+		// inherit the default namespace, encoding/xml would declare it again on every element
+		start.Name.Space = ""
+	case e.node.Name.Space == "":
+		start.Attr = append([]xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: ""}}, start.Attr...)
+	}
+	return start
+}
+
+func (e xmlElement) Value() any {
+	return xmlContent{e.node}
+}
+
+// nodeContent returns the content of a node as value for the request encoder
+func nodeContent(n *xmlNode) []any {
+	content := make([]any, 0, len(n.Children))
+	for _, c := range n.Children {
+		if c.isText() {
+			content = append(content, c.Text)
+		} else {
+			content = append(content, xmlElement{c, n.Name.Space})
+		}
+	}
+	return content
+}
+
+// xmlContent marshals the content of a node into the start element it is given
+type xmlContent struct {
+	node *xmlNode
+}
+
+func (c xmlContent) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, child := range nodeContent(c.node) {
+		var err error
+		switch child := child.(type) {
+		case string:
+			err = e.EncodeToken(xml.CharData(child))
+		case xmlElement:
+			err = e.EncodeElement(child.Value(), child.Name())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
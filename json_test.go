@@ -0,0 +1,148 @@
+package gosoap
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestFromJSON(t *testing.T) {
+	t.Parallel()
+	server, reqBody := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/order.wsdl", server, Config{ValidateRequests: true})
+
+	req, err := client.RequestFromJSON("PlaceOrder", []byte(`{"PlaceOrder": {
+		"Note": null,
+		"Item": [{"@sku": "a", "Quantity": 2}, {"@sku": "b", "Quantity": 10}],
+		"Status": "new",
+		"Customer": "ABC1234"
+	}}`))
+	require.NoError(t, err)
+	_, err = client.Do(context.Background(), req)
+	require.Error(t, err, "Note isn't nillable")
+
+	req, err = client.RequestFromJSON("PlaceOrder", []byte(`{"PlaceOrder": {
+		"Item": [{"@sku": "a", "Quantity": 2}, {"@sku": "b", "Quantity": 10}],
+		"Status": "new",
+		"Customer": "ABC1234"
+	}}`))
+	require.NoError(t, err)
+	_, err = client.Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+    <soap:Body>
+        <PlaceOrder xmlns="http://example.com/order">
+            <Customer>ABC1234</Customer>
+            <Status>new</Status>
+            <Item sku="a">
+                <Quantity>2</Quantity>
+            </Item>
+            <Item sku="b">
+                <Quantity>10</Quantity>
+            </Item>
+        </PlaceOrder>
+    </soap:Body>
+</soap:Envelope>`, string(*reqBody))
+
+	_, err = client.RequestFromJSON("PlaceOrder", []byte(`{"PlaceOrder": {"Customer": "ABC1234", "Colour": "red"}}`))
+	assert.EqualError(t, err, `/PlaceOrder: unknown element "Colour"`)
+	_, err = client.RequestFromJSON("PlaceOrder", []byte(`{"GetOrder": {}}`))
+	assert.EqualError(t, err, `unknown body element "GetOrder"`)
+	_, err = client.RequestFromJSON("PlaceOrder", []byte(`{"PlaceOrder": {"Item": {"Quantity": {"value": 1}}}}`))
+	assert.EqualError(t, err, `/PlaceOrder/Item/Quantity: unknown element "value"`)
+
+	req, err = client.RequestFromJSON("PlaceOrder", []byte(`{"PlaceOrder": {
+		"Item": [{"@sku": "a", "@{http://www.w3.org/XML/1998/namespace}lang": "en", "Quantity": 2}],
+		"Status": "new",
+		"Customer": "ABC1234"
+	}}`))
+	require.NoError(t, err)
+	_, err = client.Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Contains(t, string(*reqBody), `<Item sku="a" xml:lang="en">`)
+
+	overloaded := newTestClient(t, "./testdata/overload.wsdl", server, Config{})
+	req, err = overloaded.RequestFromJSON("Lookup", []byte(`{"LookupByName": {"Name": "ACME"}}`), WithOperationNames("ByName", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "ByName", req.InputName)
+	_, err = overloaded.Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Contains(t, string(*reqBody), `<LookupByName xmlns="http://example.com/overload">`)
+	_, err = overloaded.RequestFromJSON("Lookup", []byte(`{"LookupByName": {"Name": "ACME"}}`), WithOperationNames("ById", ""))
+	assert.EqualError(t, err, `unknown body element "LookupByName"`)
+
+	encoded := newTestClient(t, "./testdata/rpc.wsdl", server, Config{})
+	_, err = encoded.RequestFromJSON("findItems", []byte(`{"findItems": {}}`))
+	assert.EqualError(t, err, `operation "findItems" uses the SOAP encoding, it can't be converted from JSON`)
+}
+
+func TestResponseJSON(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <soap:Body>
+    <GetOrderResponse xmlns="http://example.com/order">
+      <OrderID>9007199254740993</OrderID>
+      <Placed>2024-03-01T10:30:00</Placed>
+      <Paid>false</Paid>
+      <Total currency="EUR">12.10</Total>
+      <Tags>1 2</Tags>
+      <Item sku="a" lang="de" xml:lang="en"><Quantity>2</Quantity></Item>
+      <Note xsi:nil="true"/>
+      <x:Trace xmlns:x="urn:trace">abc</x:Trace>
+    </GetOrderResponse>
+  </soap:Body>
+</soap:Envelope>`)
+	}))
+	t.Cleanup(server.Close)
+	client := newTestClient(t, "./testdata/order.wsdl", server, Config{})
+
+	res, err := client.Call(context.Background(), "GetOrder", Params{"OrderID": 1})
+	require.NoError(t, err)
+	data, err := res.JSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"GetOrderResponse": {
+		"OrderID": 9007199254740993,
+		"Placed": "2024-03-01T10:30:00",
+		"Paid": false,
+		"Total": {"@currency": "EUR", "#text": 12.1},
+		"Tags": [1, 2],
+		"Item": [{"@sku": "a", "@lang": "de", "@{http://www.w3.org/XML/1998/namespace}lang": "en", "Quantity": 2}],
+		"Note": null,
+		"{urn:trace}Trace": "abc"
+	}}`, string(data))
+	assert.Contains(t, string(data), `"#text":12.10`)
+
+	root, err := res.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, "en", root.Get("GetOrderResponse/Item/@{http://www.w3.org/XML/1998/namespace}lang"))
+	assert.Equal(t, "de", root.Get("GetOrderResponse/Item/@lang"))
+}
+
+func TestJSONScalar(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		value any
+		text  string
+		want  any
+	}{
+		{big.NewRat(3, 2), "1.50", json.Number("1.50")},
+		{big.NewRat(1, 2), " .5 ", json.Number("0.5")},
+		{big.NewInt(7), "+007", json.Number("7")},
+		{1e3, "1.0E3", json.Number("1.0E3")},
+		{0.1, "0.10000000000000000001", json.Number("0.10000000000000000001")},
+		{1.5, "+1.5", json.Number("1.5")},
+		{math.Inf(1), "INF", "INF"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, jsonScalar(tt.value, tt.text), tt.text)
+	}
+}
@@ -0,0 +1,187 @@
+package gosoap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// see https://www.w3.org/TR/soap12-part2/#soapenc
+const soap12EncodingNS = "http://www.w3.org/2003/05/soap-encoding"
+
+// maxInlinedSize limits the estimated memory in bytes used by the copies made when values are inlined.
+// Values referenced several times by values which are referenced several times themselves grow
+// exponentially, a small response could otherwise exhaust the memory.
+const maxInlinedSize = 32 << 20
+
+// resolveReferences inlines SOAP encoded multi-reference values so the body can be decoded with encoding/xml.
+// SOAP 1.1 accessors reference values with href="#id", the referenced values are usually top level
+// multiRef elements that are dropped after inlining.
+// SOAP 1.2 accessors reference values with enc:ref="id".
+// If the body contains no references the nodes are returned unchanged.
+// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383513
+func resolveReferences(roots []*xmlNode) ([]*xmlNode, error) {
+	r := &refResolver{
+		budget:     maxInlinedSize,
+		ids:        map[string]*xmlNode{},
+		resolving:  map[string]bool{},
+		resolved:   map[string]bool{},
+		referenced: map[*xmlNode]bool{},
+	}
+	for _, root := range roots {
+		r.index(root)
+	}
+	if !r.hasRefs {
+		return roots, nil
+	}
+
+	var remaining []*xmlNode
+	for _, root := range roots {
+		if err := r.resolve(root); err != nil {
+			return nil, err
+		}
+	}
+	for _, root := range roots {
+		if !r.referenced[root] {
+			remaining = append(remaining, root)
+		}
+	}
+	return remaining, nil
+}
+
+type refResolver struct {
+	// budget is the remaining size of the copies the resolver may make
+	budget     int
+	ids        map[string]*xmlNode
+	hasRefs    bool
+	resolving  map[string]bool
+	resolved   map[string]bool
+	referenced map[*xmlNode]bool
+}
+
+func (r *refResolver) index(n *xmlNode) {
+	if id, ok := nodeID(n); ok {
+		r.ids[id] = n
+	}
+	if _, ok := nodeRef(n); ok {
+		r.hasRefs = true
+	}
+	for _, c := range n.elements() {
+		r.index(c)
+	}
+}
+
+// nodeID returns the identifier of a value that can be referenced
+func nodeID(n *xmlNode) (string, bool) {
+	if id, ok := n.attr("", "id"); ok {
+		return id, true
+	}
+	return n.attr(soap12EncodingNS, "id")
+}
+
+// nodeRef returns the identifier of the value referenced by an accessor
+func nodeRef(n *xmlNode) (string, bool) {
+	if href, ok := n.attr("", "href"); ok && strings.HasPrefix(href, "#") {
+		return strings.TrimPrefix(href, "#"), true
+	}
+	return n.attr(soap12EncodingNS, "ref")
+}
+
+// resolve replaces the contents of referencing accessors in the subtree of n with copies of the referenced values
+func (r *refResolver) resolve(n *xmlNode) error {
+	if id, ok := nodeRef(n); ok {
+		target, ok := r.ids[id]
+		if !ok {
+			return fmt.Errorf("could not resolve reference to unknown id %q", id)
+		}
+		if err := r.resolveID(id, target); err != nil {
+			return err
+		}
+		r.referenced[target] = true
+		size := 0
+		for _, c := range target.Children {
+			size += nodeSize(c)
+		}
+		if r.budget -= size; r.budget < 0 {
+			return fmt.Errorf("multi-reference values expand to more than %d bytes", maxInlinedSize)
+		}
+		inline(n, target)
+	}
+	for _, c := range n.elements() {
+		if err := r.resolve(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveID resolves the references inside a referenced value once, a value that references itself is a cycle
+// that can't be represented as a tree.
+func (r *refResolver) resolveID(id string, target *xmlNode) error {
+	if r.resolved[id] {
+		return nil
+	}
+	if r.resolving[id] {
+		return fmt.Errorf("cyclic reference to id %q can't be inlined", id)
+	}
+	r.resolving[id] = true
+	defer delete(r.resolving, id)
+	if err := r.resolve(target); err != nil {
+		return err
+	}
+	r.resolved[id] = true
+	return nil
+}
+
+// inline copies the attributes and children of a referenced value into the accessor referencing it.
+// The accessor keeps its name, the reference and identifier attributes are dropped.
+func inline(accessor, target *xmlNode) {
+	var attrs []xml.Attr
+	for _, a := range accessor.Attr {
+		if !isRefAttr(a.Name.Space, a.Name.Local) {
+			attrs = append(attrs, a)
+		}
+	}
+	for _, a := range target.Attr {
+		if !isRefAttr(a.Name.Space, a.Name.Local) && !hasAttr(attrs, a) {
+			attrs = append(attrs, a)
+		}
+	}
+	accessor.Attr = attrs
+	accessor.Children = nil
+	for _, c := range target.Children {
+		accessor.Children = append(accessor.Children, c.clone())
+	}
+}
+
+// nodeSize estimates the memory used by a subtree in bytes
+func nodeSize(n *xmlNode) int {
+	const nodeOverhead = 64
+	size := nodeOverhead + len(n.Name.Local) + len(n.Text)
+	for _, a := range n.Attr {
+		size += len(a.Name.Local) + len(a.Value)
+	}
+	for _, c := range n.Children {
+		size += nodeSize(c)
+	}
+	return size
+}
+
+func isRefAttr(space, local string) bool {
+	switch {
+	case space == "" && (local == "href" || local == "id"):
+		return true
+	case space == soap12EncodingNS && (local == "ref" || local == "id"):
+		return true
+	}
+	return false
+}
+
+func hasAttr(attrs []xml.Attr, attr xml.Attr) bool {
+	for _, a := range attrs {
+		if a.Name == attr.Name {
+			return true
+		}
+	}
+	return false
+}
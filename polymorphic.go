@@ -0,0 +1,279 @@
+package gosoap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TypeRegistry maps XSD type and element names to the Go types implementing them.
+// Clients use the types registered with RegisterType and RegisterElement unless Config.Types is set.
+type TypeRegistry struct {
+	mu sync.RWMutex
+	// types registered for xsi:type values and the reverse mapping used by the encoder
+	types     map[xml.Name]reflect.Type
+	typeNames map[reflect.Type]xml.Name
+	// types registered for elements of substitution groups and the reverse mapping used by the encoder
+	elements     map[xml.Name]reflect.Type
+	elementNames map[reflect.Type]xml.Name
+}
+
+// NewTypeRegistry returns an empty registry
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		types:        map[xml.Name]reflect.Type{},
+		typeNames:    map[reflect.Type]xml.Name{},
+		elements:     map[xml.Name]reflect.Type{},
+		elementNames: map[reflect.Type]xml.Name{},
+	}
+}
+
+// defaultRegistry holds the types registered with RegisterType and RegisterElement
+var defaultRegistry = NewTypeRegistry()
+
+// coderScope is the state shared by the Polymorphic values coded by an encoder or decoder of a client
+type coderScope struct {
+	types *TypeRegistry
+	// prefixes are the prefixes allocated for the xsi:type values of an envelope
+	prefixes namespacePrefixes
+}
+
+// scopes maps the encoders and decoders of clients to their scope, Polymorphic values coded
+// by any other encoder or decoder use the default registry
+var scopes sync.Map
+
+// scopeOf returns the scope of the encoder or decoder of a Polymorphic value
+func scopeOf(coder any) *coderScope {
+	if s, ok := scopes.Load(coder); ok {
+		return s.(*coderScope)
+	}
+	return &coderScope{types: defaultRegistry}
+}
+
+// orDefault returns the registry, or the default registry if r is nil
+func (r *TypeRegistry) orDefault() *TypeRegistry {
+	if r == nil {
+		return defaultRegistry
+	}
+	return r
+}
+
+// unmarshal is like xml.Unmarshal, Polymorphic values in v are resolved with types
+func unmarshal(data []byte, v any, types *TypeRegistry) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	if types != nil {
+		scopes.Store(d, &coderScope{types: types})
+		defer scopes.Delete(d)
+	}
+	return d.Decode(v)
+}
+
+// RegisterType records the Go type of value as the implementation of the XSD type name.
+// The encoder annotates values of the type with xsi:type, the decoder instantiates the type
+// for Polymorphic fields whose element carries the xsi:type. value can be a pointer, then
+// Polymorphic fields get a pointer to the decoded value.
+// Like gob.Register it is meant to be called during initialization and panics if
+// the name or the type is registered twice with different counterparts.
+func RegisterType(name xml.Name, value any) {
+	defaultRegistry.RegisterType(name, value)
+}
+
+// RegisterType is like the package level RegisterType for the clients using the registry
+func (r *TypeRegistry) RegisterType(name xml.Name, value any) {
+	r.register(r.types, r.typeNames, name, value)
+}
+
+// RegisterElement records the Go type of value as the implementation of a global element,
+// typically a member of a substitution group. The decoder instantiates the type for Polymorphic
+// fields without xsi:type by the name of their element, the encoder renames Polymorphic values
+// of the type to the element.
+func RegisterElement(name xml.Name, value any) {
+	defaultRegistry.RegisterElement(name, value)
+}
+
+// RegisterElement is like the package level RegisterElement for the clients using the registry
+func (r *TypeRegistry) RegisterElement(name xml.Name, value any) {
+	r.register(r.elements, r.elementNames, name, value)
+}
+
+func (r *TypeRegistry) register(byName map[xml.Name]reflect.Type, byType map[reflect.Type]xml.Name, name xml.Name, value any) {
+	if name.Local == "" {
+		panic("gosoap: registering type without a name")
+	}
+	t := reflect.TypeOf(value)
+	if t == nil {
+		panic("gosoap: registering nil value")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if registered, ok := byName[name]; ok && registered != t {
+		panic(fmt.Sprintf("gosoap: registering duplicate types for %s: %s != %s", formatName(name), registered, t))
+	}
+	base := baseType(t)
+	if registered, ok := byType[base]; ok && registered != name {
+		panic(fmt.Sprintf("gosoap: registering duplicate names for %s: %s != %s", t, formatName(registered), formatName(name)))
+	}
+	byName[name] = t
+	byType[base] = name
+}
+
+func (r *TypeRegistry) lookup(byName map[xml.Name]reflect.Type, name xml.Name) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := byName[name]
+	return t, ok
+}
+
+func (r *TypeRegistry) nameOf(byType map[reflect.Type]xml.Name, t reflect.Type) (xml.Name, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := byType[baseType(t)]
+	return name, ok
+}
+
+// typeName returns the XSD type name a Go type was registered for
+func (r *TypeRegistry) typeName(t reflect.Type) (xml.Name, bool) {
+	return r.nameOf(r.typeNames, t)
+}
+
+func baseType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func formatName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return "{" + name.Space + "}" + name.Local
+}
+
+// Polymorphic holds a value of an abstract type or the head of a substitution group, T is usually an interface
+// implemented by the registered concrete types.
+// When decoding, the concrete type is selected by the xsi:type of the element and else by the element name,
+// see RegisterType, RegisterElement and Config.Types. If neither is registered and T is not an interface the element is
+// decoded into T. Fields for substitution groups need the ",any" tag to match elements of any name.
+// When encoding, values of registered types are annotated with their xsi:type and renamed to their registered element.
+// see https://www.w3.org/TR/xmlschema-1/#xsi_type
+type Polymorphic[T any] struct {
+	Value T
+}
+
+func (p *Polymorphic[T]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	t, err := concreteType(scopeOf(d).types, start)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		var value T
+		if reflect.TypeOf(&value).Elem().Kind() == reflect.Interface {
+			return fmt.Errorf("no Go type registered for element %s without xsi:type", formatName(start.Name))
+		}
+		if err := d.DecodeElement(&value, &start); err != nil {
+			return err
+		}
+		p.Value = value
+		return nil
+	}
+
+	ptr := reflect.New(baseType(t))
+	if err := d.DecodeElement(ptr.Interface(), &start); err != nil {
+		return err
+	}
+	v := ptr
+	if t.Kind() != reflect.Pointer {
+		v = ptr.Elem()
+	}
+	value, ok := v.Interface().(T)
+	if !ok {
+		var zero T
+		return fmt.Errorf("registered type %s of element %s is not assignable to %s", t, formatName(start.Name), reflect.TypeOf(&zero).Elem())
+	}
+	p.Value = value
+	return nil
+}
+
+// concreteType returns the registered type for the xsi:type or the name of an element, nil if neither is registered.
+// An xsi:type that is not registered is an error unless it is a built-in type.
+func concreteType(types *TypeRegistry, start xml.StartElement) (reflect.Type, error) {
+	for _, a := range start.Attr {
+		if a.Name.Space != xsiNS || a.Name.Local != "type" {
+			continue
+		}
+		name := namespaces(start.Attr).resolve(strings.TrimSpace(a.Value))
+		if t, ok := types.lookup(types.types, name); ok {
+			return t, nil
+		}
+		if !isBuiltinType(name) {
+			return nil, fmt.Errorf("no Go type registered for xsi:type %s of element %s", formatName(name), formatName(start.Name))
+		}
+	}
+	if t, ok := types.lookup(types.elements, start.Name); ok {
+		return t, nil
+	}
+	return nil, nil
+}
+
+func (p Polymorphic[T]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	v := reflect.ValueOf(p.Value)
+	if !v.IsValid() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return nil
+	}
+	scope := scopeOf(e)
+	types := scope.types
+	if name, ok := types.nameOf(types.elementNames, v.Type()); ok {
+		start.Name = name
+	}
+	if name, ok := types.typeName(v.Type()); ok {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Space: "", Local: "xmlns:xsi"}, Value: xsiNS})
+		start.Attr = append(start.Attr, typeAttrs(name, scope.prefixes)...)
+	}
+	return e.EncodeElement(p.Value, start)
+}
+
+// declareTypePrefixes declares the prefixes used by xsi:type values on the elements carrying them,
+// scope are the namespace declarations in scope of the parent of n. It reports whether a declaration was added.
+func declareTypePrefixes(n *xmlNode, scope namespaces) bool {
+	if n.isText() {
+		return false
+	}
+	declared := false
+	var own namespaces
+	for _, a := range n.Attr {
+		if a.Name.Space == "xmlns" {
+			own = append(own, a)
+		}
+	}
+	if len(own) > 0 {
+		scope = append(append(namespaces{}, scope...), own...)
+	}
+	if value, ok := n.attr(xsiNS, "type"); ok {
+		if prefix, _, found := strings.Cut(strings.TrimSpace(value), ":"); found && !isDeclared(own, prefix) {
+			name := scope.resolve(strings.TrimSpace(value))
+			if name.Space != "" {
+				n.Attr = append(n.Attr, xml.Attr{Name: xml.Name{Space: "xmlns", Local: prefix}, Value: name.Space})
+				declared = true
+			}
+		}
+	}
+	for _, c := range n.Children {
+		if declareTypePrefixes(c, scope) {
+			declared = true
+		}
+	}
+	return declared
+}
+
+func isDeclared(ns namespaces, prefix string) bool {
+	for _, a := range ns {
+		if a.Name.Local == prefix {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package gosoap
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Vehicle interface {
+	Wheels() int
+}
+
+type Car struct {
+	Make string `xml:"Make"`
+}
+
+func (Car) Wheels() int { return 4 }
+
+type Motorbike struct {
+	Make string `xml:"Make"`
+}
+
+func (*Motorbike) Wheels() int { return 2 }
+
+type Truck struct {
+	Axles int `xml:"Axles"`
+}
+
+func (t Truck) Wheels() int { return t.Axles * 2 }
+
+const insuranceNS = "urn:insurance"
+
+func init() {
+	RegisterType(xml.Name{Space: insuranceNS, Local: "CarType"}, Car{})
+	RegisterType(xml.Name{Space: insuranceNS, Local: "MotorbikeType"}, &Motorbike{})
+	RegisterElement(xml.Name{Space: insuranceNS, Local: "Truck"}, Truck{})
+}
+
+type Policy struct {
+	XMLName xml.Name               `xml:"urn:insurance Policy"`
+	Insured []Polymorphic[Vehicle] `xml:"Vehicle"`
+	Extra   Polymorphic[Vehicle]   `xml:",any"`
+}
+
+func TestPolymorphicDecode(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:ins="urn:insurance">
+  <soap:Body>
+    <ins:Policy>
+      <ins:Vehicle xsi:type="ins:CarType"><ins:Make>Fiat</ins:Make></ins:Vehicle>
+      <ins:Vehicle xmlns:v="urn:insurance" xsi:type="v:MotorbikeType"><ins:Make>Ducati</ins:Make></ins:Vehicle>
+      <ins:Truck><ins:Axles>3</ins:Axles></ins:Truck>
+    </ins:Policy>
+  </soap:Body>
+</soap:Envelope>`))
+	}))
+	t.Cleanup(server.Close)
+	client := newTestClient(t, "./testdata/quote.wsdl", server, Config{})
+
+	res, err := client.Call(context.Background(), "GetQuote", Params{})
+	require.NoError(t, err)
+	var policy Policy
+	require.NoError(t, res.Unmarshal(&policy))
+	require.Len(t, policy.Insured, 2)
+	assert.Equal(t, Car{Make: "Fiat"}, policy.Insured[0].Value)
+	assert.Equal(t, &Motorbike{Make: "Ducati"}, policy.Insured[1].Value)
+	assert.Equal(t, Truck{Axles: 3}, policy.Extra.Value)
+	assert.Equal(t, 6, policy.Extra.Value.Wheels())
+}
+
+func TestPolymorphicErrors(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		body string
+		err  string
+	}{
+		{
+			name: "unregistered xsi:type",
+			body: `<Policy xmlns="urn:insurance" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:ins="urn:insurance"><Vehicle xsi:type="ins:BoatType"/></Policy>`,
+			err:  "no Go type registered for xsi:type {urn:insurance}BoatType of element {urn:insurance}Vehicle",
+		},
+		{
+			name: "abstract without xsi:type",
+			body: `<Policy xmlns="urn:insurance"><Vehicle/></Policy>`,
+			err:  "no Go type registered for element {urn:insurance}Vehicle without xsi:type",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := &Response{Body: []byte(tc.body)}
+			var policy Policy
+			assert.EqualError(t, res.Unmarshal(&policy), tc.err)
+		})
+	}
+}
+
+func TestPolymorphicEncode(t *testing.T) {
+	t.Parallel()
+	server, reqBody := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/quote.wsdl", server, Config{})
+
+	policy := Policy{
+		Insured: []Polymorphic[Vehicle]{{Value: Car{Make: "Fiat"}}, {Value: &Motorbike{Make: "Ducati"}}},
+		Extra:   Polymorphic[Vehicle]{Value: Truck{Axles: 2}},
+	}
+	_, err := client.Call(context.Background(), "GetQuote", policy)
+	require.NoError(t, err)
+	assert.Contains(t, string(*reqBody), `<Vehicle xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="ns1:CarType" xmlns:ns1="urn:insurance">`)
+	assert.Contains(t, string(*reqBody), `<Vehicle xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="ns1:MotorbikeType" xmlns:ns1="urn:insurance">`)
+	assert.Contains(t, string(*reqBody), `<Truck xmlns="urn:insurance">`)
+
+	// the request decodes to the same values
+	_, body, _ := strings.Cut(string(*reqBody), "<soap:Body>")
+	body, _, _ = strings.Cut(body, "</soap:Body>")
+	res := &Response{Body: []byte(body)}
+	var decoded struct {
+		Policy Policy
+	}
+	require.NoError(t, res.Unmarshal(&decoded))
+	assert.Equal(t, policy.Insured, decoded.Policy.Insured)
+	assert.Equal(t, policy.Extra, decoded.Policy.Extra)
+}
+
+func TestRegisterTypeEncoded(t *testing.T) {
+	t.Parallel()
+	server, reqBody := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/rpc.wsdl", server, Config{})
+
+	_, err := client.Call(context.Background(), "findItems", Params{"vehicle": Car{Make: "Fiat"}})
+	require.NoError(t, err)
+	assert.Contains(t, string(*reqBody), `<vehicle xsi:type="ns1:CarType" xmlns:ns1="urn:insurance">`)
+
+	types := NewTypeRegistry()
+	types.RegisterType(xml.Name{Space: insuranceNS, Local: "CarType"}, Car{})
+	types.RegisterType(xml.Name{Space: "urn:marine", Local: "BoatType"}, Boat{})
+	client = newTestClient(t, "./testdata/rpc.wsdl", server, Config{Types: types})
+	_, err = client.Call(context.Background(), "findItems", Params{"a": Car{Make: "Fiat"}, "b": Boat{Name: "Ana"}, "c": Car{Make: "Lada"}})
+	require.NoError(t, err)
+	assert.Contains(t, string(*reqBody), `<a xsi:type="ns1:CarType" xmlns:ns1="urn:insurance">`)
+	assert.Contains(t, string(*reqBody), `<b xsi:type="ns2:BoatType" xmlns:ns2="urn:marine">`, "every namespace of an envelope gets its own prefix")
+	assert.Contains(t, string(*reqBody), `<c xsi:type="ns1:CarType" xmlns:ns1="urn:insurance">`)
+}
+
+type Boat struct {
+	Name string `xml:"Name"`
+}
+
+func (Boat) Wheels() int { return 0 }
+
+func TestTypeRegistry(t *testing.T) {
+	t.Parallel()
+	types := NewTypeRegistry()
+	types.RegisterType(xml.Name{Space: insuranceNS, Local: "BoatType"}, Boat{})
+	body := `<Policy xmlns="urn:insurance" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:ins="urn:insurance"><Vehicle xsi:type="ins:BoatType"><Name>Ana</Name></Vehicle></Policy>`
+
+	var policy Policy
+	require.NoError(t, (&Response{Body: []byte(body), types: types}).Unmarshal(&policy))
+	require.Len(t, policy.Insured, 1)
+	assert.Equal(t, Boat{Name: "Ana"}, policy.Insured[0].Value)
+	assert.EqualError(t, (&Response{Body: []byte(body)}).Unmarshal(&policy),
+		"no Go type registered for xsi:type {urn:insurance}BoatType of element {urn:insurance}Vehicle", "types of a registry are not registered globally")
+	assert.Error(t, (&Response{Body: []byte(`<Policy xmlns="urn:insurance"><Truck/></Policy>`), types: types}).Unmarshal(&policy),
+		"globally registered types are not in a registry")
+
+	server, reqBody := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/quote.wsdl", server, Config{Types: types})
+	_, err := client.Call(context.Background(), "GetQuote", Policy{Insured: []Polymorphic[Vehicle]{{Value: Boat{Name: "Ana"}}}})
+	require.NoError(t, err)
+	assert.Contains(t, string(*reqBody), `xsi:type="ns1:BoatType"`)
+}
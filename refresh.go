@@ -0,0 +1,160 @@
+package gosoap
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// WSDLChange describes how a refreshed WSDL differs from the WSDL the client used before
+type WSDLChange struct {
+	OldAddress   string
+	NewAddress   string
+	OldNamespace string
+	NewNamespace string
+	// AddedOperations and RemovedOperations are the operations of the binding that were added or removed,
+	// overloaded operations are listed with their input name, e.g. "Lookup(ById)"
+	AddedOperations   []string
+	RemovedOperations []string
+	// SOAPActions holds the operations whose SOAPAction changed
+	SOAPActions map[string]SOAPActionChange
+}
+
+type SOAPActionChange struct {
+	Old string
+	New string
+}
+
+// Changed reports whether anything the client depends on changed
+func (c *WSDLChange) Changed() bool {
+	return c.OldAddress != c.NewAddress || c.OldNamespace != c.NewNamespace ||
+		len(c.AddedOperations) > 0 || len(c.RemovedOperations) > 0 || len(c.SOAPActions) > 0
+}
+
+// Refresh loads the WSDL again and switches the client to the new binding and endpoint.
+// Requests in flight complete with the WSDL they started with.
+func (c *Client) Refresh(ctx context.Context) (*WSDLChange, error) {
+	if c.source == nil {
+		return nil, errors.New("client has no WSDL source")
+	}
+	definitions, err := getWSDLDefinitions(ctx, c.source, &c.config)
+	if err != nil {
+		return nil, err
+	}
+	state, err := newWSDLState(definitions, &c.config)
+	if err != nil {
+		return nil, err
+	}
+	return c.wsdl.Swap(state).diff(state), nil
+}
+
+// diff compares the state with the state that replaces it
+func (s *wsdlState) diff(next *wsdlState) *WSDLChange {
+	if s == nil {
+		s = &wsdlState{}
+	}
+	change := &WSDLChange{
+		OldAddress:   s.address,
+		NewAddress:   next.address,
+		OldNamespace: s.namespace,
+		NewNamespace: next.namespace,
+		SOAPActions:  map[string]SOAPActionChange{},
+	}
+	old, current := s.soapActions(), next.soapActions()
+	for name, action := range current {
+		previous, ok := old[name]
+		switch {
+		case !ok:
+			change.AddedOperations = append(change.AddedOperations, name)
+		case previous != action:
+			change.SOAPActions[name] = SOAPActionChange{Old: previous, New: action}
+		}
+	}
+	for name := range old {
+		if _, ok := current[name]; !ok {
+			change.RemovedOperations = append(change.RemovedOperations, name)
+		}
+	}
+	slices.Sort(change.AddedOperations)
+	slices.Sort(change.RemovedOperations)
+	return change
+}
+
+// soapActions maps the operations of the binding to their SOAPAction
+func (s *wsdlState) soapActions() map[string]string {
+	actions := map[string]string{}
+	if s.binding == nil {
+		return actions
+	}
+	for _, o := range s.binding.Operations {
+		name := o.Name
+		if input, _ := o.declaredNames(); input != "" {
+			name += "(" + input + ")"
+		}
+		actions[name], _ = s.binding.soapAction(o, o.Name)
+	}
+	return actions
+}
+
+// refresher reloads the WSDL of a client in the background until the client is closed
+type refresher struct {
+	// trigger requests a refresh, requests are coalesced while a refresh is pending
+	trigger chan struct{}
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func (c *Client) startRefresher() {
+	if c.config.RefreshInterval <= 0 && c.config.RefreshOn == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.refresher = &refresher{trigger: make(chan struct{}, 1), cancel: cancel, done: make(chan struct{})}
+	go c.refreshLoop(ctx, c.refresher)
+}
+
+func (c *Client) refreshLoop(ctx context.Context, r *refresher) {
+	defer close(r.done)
+	var tick <-chan time.Time
+	if c.config.RefreshInterval > 0 {
+		ticker := time.NewTicker(c.config.RefreshInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-r.trigger:
+		}
+		change, err := c.Refresh(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if c.config.OnRefresh != nil && (err != nil || change.Changed()) {
+			c.config.OnRefresh(change, err)
+		}
+	}
+}
+
+// triggerRefresh requests a background refresh without waiting for it
+func (c *Client) triggerRefresh() {
+	if c.refresher == nil {
+		return
+	}
+	select {
+	case c.refresher.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the background refresh of the WSDL, the client can still be used afterwards
+func (c *Client) Close() error {
+	if c.refresher != nil {
+		c.refresher.cancel()
+		<-c.refresher.done
+	}
+	return nil
+}
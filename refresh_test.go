@@ -0,0 +1,144 @@
+package gosoap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type refreshResult struct {
+	change *WSDLChange
+	err    error
+}
+
+func TestRefresh(t *testing.T) {
+	t.Parallel()
+	quote, err := os.ReadFile("testdata/quote.wsdl")
+	require.NoError(t, err)
+
+	var wsdl atomic.Value
+	var mu sync.Mutex
+	var path, action string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wsdl":
+			_, _ = w.Write(wsdl.Load().([]byte))
+		case "/v1", "/v2":
+			if r.URL.Path == "/v1" && strings.Contains(string(wsdl.Load().([]byte)), "/v2") {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			mu.Lock()
+			path, action = r.URL.Path, r.Header.Get("SOAPAction")
+			mu.Unlock()
+			_, _ = w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body/></soap:Envelope>`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	last := func() (string, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		return path, action
+	}
+
+	v1 := strings.ReplaceAll(string(quote), `location="http://example.com/quote"`, `location="`+server.URL+`/v1"`)
+	v2 := strings.ReplaceAll(v1, server.URL+"/v1", server.URL+"/v2")
+	v2 = strings.ReplaceAll(v2, "http://example.com/quote/GetQuote\"", "http://example.com/quote/v2/GetQuote\"")
+	v2 = strings.ReplaceAll(v2, `<wsdl:operation name="Convert">
+      <soap:operation`, `<wsdl:operation name="ConvertCurrency">
+      <soap:operation`)
+	wsdl.Store([]byte(v1))
+
+	refreshed := make(chan refreshResult, 1)
+	client, err := NewClient(SourceFromURI(server.URL+"/wsdl"), &Config{
+		Client: server.Client(),
+		RefreshOn: func(err error) bool {
+			return true
+		},
+		OnRefresh: func(change *WSDLChange, err error) {
+			refreshed <- refreshResult{change, err}
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	_, err = client.Call(context.Background(), "GetQuote", Params{"Symbol": "ACME"})
+	require.NoError(t, err)
+	p, a := last()
+	assert.Equal(t, "/v1", p)
+	assert.Equal(t, "http://example.com/quote/GetQuote", a)
+
+	wsdl.Store([]byte(v2))
+	_, err = client.Call(context.Background(), "GetQuote", Params{"Symbol": "ACME"})
+	require.Error(t, err)
+
+	select {
+	case r := <-refreshed:
+		require.NoError(t, r.err)
+		assert.Equal(t, &WSDLChange{
+			OldAddress:        server.URL + "/v1",
+			NewAddress:        server.URL + "/v2",
+			OldNamespace:      "http://example.com/quote",
+			NewNamespace:      "http://example.com/quote",
+			AddedOperations:   []string{"ConvertCurrency"},
+			RemovedOperations: []string{"Convert"},
+			SOAPActions: map[string]SOAPActionChange{
+				"GetQuote": {Old: "http://example.com/quote/GetQuote", New: "http://example.com/quote/v2/GetQuote"},
+			},
+		}, r.change)
+	case <-time.After(5 * time.Second):
+		t.Fatal("WSDL was not refreshed")
+	}
+
+	_, err = client.Call(context.Background(), "GetQuote", Params{"Symbol": "ACME"})
+	require.NoError(t, err)
+	p, a = last()
+	assert.Equal(t, "/v2", p)
+	assert.Equal(t, "http://example.com/quote/v2/GetQuote", a)
+
+	change, err := client.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.False(t, change.Changed())
+}
+
+func TestRefreshInterval(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/quote.wsdl")
+	}))
+	t.Cleanup(server.Close)
+
+	refreshed := make(chan refreshResult, 1)
+	client, err := NewClient(SourceFromURI(server.URL), &Config{
+		Client:          server.Client(),
+		RefreshInterval: 10 * time.Millisecond,
+		OnRefresh: func(change *WSDLChange, err error) {
+			select {
+			case refreshed <- refreshResult{change, err}:
+			default:
+			}
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	server.Close()
+	select {
+	case r := <-refreshed:
+		assert.ErrorContains(t, r.err, "could not fetch WSDL resource")
+		assert.Nil(t, r.change)
+	case <-time.After(5 * time.Second):
+		t.Fatal("WSDL was not refreshed")
+	}
+	assert.NotNil(t, client.Definitions().Service("QuoteService"), "the previous WSDL is kept")
+	require.NoError(t, client.Close())
+}
@@ -1,14 +1,41 @@
 package gosoap
 
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
 // A representation of a SOAP Request
 type Request struct {
 	// wsdl operation name, this will be used to map to a SOAP action
 	// https://www.w3.org/TR/2001/NOTE-wsdl-20010315#_soap:operation
 	WSDLOperation string
+	// InputName and OutputName select one of several operations with the same name,
+	// they can be left empty if the operation is not overloaded
+	// see https://www.w3.org/TR/2001/NOTE-wsdl-20010315#_names
+	InputName  string
+	OutputName string
 	// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383503
 	Body any
 	// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383497
 	HeaderEntries []any
+
+	// Endpoint overrides the address of the port
+	Endpoint string
+	// SOAPAction overrides the action derived from the binding or Config.AutoAction
+	SOAPAction string
+	// HTTPHeader is added to the HTTP request, its values replace the default headers
+	HTTPHeader http.Header
+	// Timeout limits the duration of the request in addition to the deadline of the context
+	Timeout time.Duration
+	// Username and Password override the basic authentication credentials of the Config
+	Username string
+	Password string
+	// LogRequests overrides Config.LogRequests if set
+	LogRequests *bool
 }
 
 func NewRequest(wsdlOperation string, body any, headerBlocks ...any) *Request {
@@ -18,3 +45,133 @@ func NewRequest(wsdlOperation string, body any, headerBlocks ...any) *Request {
 		HeaderEntries: headerBlocks,
 	}
 }
+
+// CallOption changes a single request, it can be passed to Do and Call as well as Invoke
+type CallOption func(req *Request)
+
+func (o CallOption) applyInvoke(inv *invocation) {
+	o(inv.request)
+}
+
+// WithHeaderEntries adds header blocks to the request, see Request.HeaderEntries
+func WithHeaderEntries(entries ...any) CallOption {
+	return func(req *Request) {
+		req.HeaderEntries = append(append([]any{}, req.HeaderEntries...), entries...)
+	}
+}
+
+// WithOperationNames selects one of several operations with the same name by the names of its input and output,
+// see Request.InputName
+func WithOperationNames(input, output string) CallOption {
+	return func(req *Request) {
+		req.InputName = input
+		req.OutputName = output
+	}
+}
+
+// WithEndpoint sends the request to address instead of the address of the port
+func WithEndpoint(address string) CallOption {
+	return func(req *Request) {
+		req.Endpoint = address
+	}
+}
+
+// WithSOAPAction sets the SOAPAction of the request
+func WithSOAPAction(action string) CallOption {
+	return func(req *Request) {
+		req.SOAPAction = action
+	}
+}
+
+// WithHTTPHeader adds a header to the HTTP request
+func WithHTTPHeader(key, value string) CallOption {
+	return func(req *Request) {
+		header := req.HTTPHeader.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Add(key, value)
+		req.HTTPHeader = header
+	}
+}
+
+// WithTimeout limits the duration of the request
+func WithTimeout(timeout time.Duration) CallOption {
+	return func(req *Request) {
+		req.Timeout = timeout
+	}
+}
+
+// WithBasicAuth sets the credentials used for basic authentication
+func WithBasicAuth(username, password string) CallOption {
+	return func(req *Request) {
+		req.Username = username
+		req.Password = password
+	}
+}
+
+// WithLogging enables or disables logging the request and response
+func WithLogging(enabled bool) CallOption {
+	return func(req *Request) {
+		req.LogRequests = &enabled
+	}
+}
+
+// HeaderEntry is a header block with its own namespace and SOAP header attributes,
+// it can be used in Request.HeaderEntries next to plain values.
+// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383497
+type HeaderEntry struct {
+	// Name of the header block element. If empty, Content has to marshal itself as
+	// an element (NamedElement or a struct with an XMLName) and that name is used.
+	Name string
+	// Namespace of the header block element, if empty the namespace of the Content element is used
+	Namespace string
+	// MustUnderstand marks the header block as mandatory for the receiver
+	// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383500
+	MustUnderstand bool
+	// Actor is the SOAP 1.1 URI of the intended receiver
+	// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383499
+	Actor string
+	// Role is the SOAP 1.2 URI of the intended receiver
+	// see https://www.w3.org/TR/soap12-part1/#soaprole
+	Role string
+	// Relay marks a SOAP 1.2 header block to be relayed if it isn't processed
+	// see https://www.w3.org/TR/soap12-part1/#soaprelay
+	Relay bool
+	// Content of the header block, encoded like a request body
+	Content any
+}
+
+func (h *HeaderEntry) name() (xml.Name, error) {
+	if h.Name != "" {
+		return xml.Name{Space: h.Namespace, Local: h.Name}, nil
+	}
+	name, ok := elementName(h.Content)
+	if !ok {
+		return xml.Name{}, errors.New("header entry has no name and its content is not a named element")
+	}
+	if h.Namespace != "" {
+		name.Space = h.Namespace
+	}
+	return name, nil
+}
+
+func (h *HeaderEntry) attrs(c *Config) []xml.Attr {
+	var attrs []xml.Attr
+	attr := func(local, value string) {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Space: "", Local: fmt.Sprintf("%s:%s", c.EnvelopePrefix, local)}, Value: value})
+	}
+	if h.MustUnderstand {
+		attr("mustUnderstand", "1")
+	}
+	if h.Actor != "" {
+		attr("actor", h.Actor)
+	}
+	if h.Role != "" {
+		attr("role", h.Role)
+	}
+	if h.Relay {
+		attr("relay", "true")
+	}
+	return attrs
+}
@@ -1,8 +1,17 @@
 package gosoap
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+const (
+	soap11EnvelopeNS = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12EnvelopeNS = "http://www.w3.org/2003/05/soap-envelope"
 )
 
 // Response Soap Response
@@ -11,6 +20,30 @@ type Response struct {
 	Body []byte
 	// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383497
 	HeaderEntries []byte
+
+	// StatusCode and Status of the HTTP response, e.g. 200 and "200 OK"
+	StatusCode int
+	Status     string
+	// HTTPHeader contains the headers of the HTTP response
+	HTTPHeader http.Header
+	// ContentType is the media type and charset of the HTTP response
+	ContentType string
+	// Sent is the time the request was sent, Duration the time it took until the response was read
+	Sent     time.Time
+	Duration time.Duration
+	// Envelope is the response exactly as it was received
+	Envelope []byte
+
+	// namespaces declared on the envelope and the body or header element, the body and header entries can use their prefixes
+	bodyNamespaces   namespaces
+	headerNamespaces namespaces
+	// envelopeNS is the namespace of the envelope, the SOAP header attributes of the header blocks are in this namespace
+	envelopeNS string
+	// wsdl and operation describe the response, Decode uses them to look up the schema of the output message
+	wsdl      *wsdlState
+	operation *wsdlOperation
+	// types resolves Polymorphic values, nil for the default registry
+	types *TypeRegistry
 }
 
 // FaultError implements error interface
@@ -31,6 +64,11 @@ func (e FaultError) Is(target error) bool {
 	return f.Fault.Code == e.Fault.Code && f.Fault.Description == e.Fault.Description
 }
 
+// Cookies parses the cookies set by the server
+func (r *Response) Cookies() []*http.Cookie {
+	return (&http.Response{Header: r.HTTPHeader}).Cookies()
+}
+
 func (r *Response) Unmarshal(v any) error {
 	if len(r.Body) == 0 {
 		return fmt.Errorf("body is empty")
@@ -45,13 +83,57 @@ func (r *Response) Unmarshal(v any) error {
 		return FaultError{Fault: fault}
 	}
 
-	return xml.Unmarshal(r.Body, v)
+	body, err := prepareBody(r.Body, r.bodyNamespaces, true)
+	if err != nil {
+		return err
+	}
+	return unmarshal(body, v, r.types)
 }
 
+// UnmarshalHeader decodes the first header block into v, see HeaderBlocks and UnmarshalHeaderBlock
+// for responses with several header blocks
 func (r *Response) UnmarshalHeader(v any) error {
 	if len(r.HeaderEntries) == 0 {
 		return fmt.Errorf("Header is empty")
 	}
 
-	return xml.Unmarshal(r.HeaderEntries, v)
+	header, err := prepareBody(r.HeaderEntries, r.headerNamespaces, false)
+	if err != nil {
+		return err
+	}
+	return unmarshal(header, v, r.types)
+}
+
+var prefixedTypeAttr = regexp.MustCompile(`:type\s*=`)
+
+// prepareBody rewrites the content of the body or header so encoding/xml can decode it: multi-reference values are
+// inlined and the prefixes of xsi:type values are declared on the elements carrying them, because an UnmarshalXML
+// method only sees the namespace declarations of its own element.
+// Content without references and undeclared xsi:type prefixes is returned unchanged.
+func prepareBody(content []byte, inherited namespaces, references bool) ([]byte, error) {
+	hasRefs := references && (bytes.Contains(content, []byte("href")) || bytes.Contains(content, []byte(soap12EncodingNS)))
+	// xsi:type is a prefixed attribute, content without any prefixed type attribute is not parsed
+	if !hasRefs && !prefixedTypeAttr.Match(content) {
+		return content, nil
+	}
+	nodes, err := parseXMLFragment(content, inherited)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the body: %w", err)
+	}
+	if hasRefs {
+		nodes, err = resolveReferences(nodes)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving multi-reference values: %w", err)
+		}
+	}
+	declared := false
+	for _, n := range nodes {
+		if declareTypePrefixes(n, inherited) {
+			declared = true
+		}
+	}
+	if !hasRefs && !declared {
+		return content, nil
+	}
+	return marshalXMLNodes(nodes)
 }
@@ -1,12 +1,19 @@
 package gosoap
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUnmarshal(t *testing.T) {
@@ -148,3 +155,183 @@ func TestIsFault(t *testing.T) {
 		assert.Equal(t, testCase.expectedIsFaultError, errors.As(testCase.err, &FaultError{}))
 	}
 }
+
+type Order struct {
+	ID       string `xml:"id"`
+	Customer struct {
+		Name string `xml:"name"`
+	} `xml:"customer"`
+	Items []struct {
+		SKU string `xml:"sku"`
+	} `xml:"items>item"`
+}
+
+func TestUnmarshalMultiRef(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		description   string
+		body          string
+		expectedError string
+	}{
+		{
+			description: "case: SOAP 1.1 multiRef",
+			body: `
+				<ns1:getOrderResponse soapenv:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/" xmlns:ns1="urn:orders">
+					<getOrderReturn href="#id0"/>
+				</ns1:getOrderResponse>
+				<multiRef id="id0" soapenc:root="0" xsi:type="ns2:Order" xmlns:ns2="urn:orders">
+					<id xsi:type="xsd:string">42</id>
+					<customer href="#id1"/>
+					<items soapenc:arrayType="ns2:Item[2]" xsi:type="soapenc:Array">
+						<item href="#id2"/>
+						<item href="#id2"/>
+					</items>
+				</multiRef>
+				<multiRef id="id1" soapenc:root="0" xsi:type="ns3:Customer" xmlns:ns3="urn:orders">
+					<name xsi:type="xsd:string">ACME &amp; Co</name>
+				</multiRef>
+				<multiRef id="id2" soapenc:root="0" xsi:type="ns4:Item" xmlns:ns4="urn:orders">
+					<sku xsi:type="xsd:string">X-1</sku>
+				</multiRef>`,
+		},
+		{
+			description: "case: SOAP 1.2 ref",
+			body: `
+				<getOrderResponse xmlns:enc="http://www.w3.org/2003/05/soap-encoding">
+					<getOrderReturn enc:ref="o1"/>
+					<order enc:id="o1">
+						<id>42</id>
+						<customer><name>ACME &amp; Co</name></customer>
+						<items><item enc:ref="i1"/><item enc:id="i1"><sku>X-1</sku></item></items>
+					</order>
+				</getOrderResponse>`,
+		},
+		{
+			description: "case: cycle",
+			body: `
+				<getOrderResponse><getOrderReturn href="#id0"/></getOrderResponse>
+				<multiRef id="id0"><customer href="#id0"/></multiRef>`,
+			expectedError: `error resolving multi-reference values: cyclic reference to id "id0" can't be inlined`,
+		},
+	}
+
+	// every value references the next one twice, the inlined body would double with every level
+	var expanding strings.Builder
+	expanding.WriteString(`<getOrderResponse><getOrderReturn href="#id0"/></getOrderResponse>`)
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&expanding, `<multiRef id="id%d"><a href="#id%d"/><b href="#id%d"/></multiRef>`, i, i+1, i+1)
+	}
+	expanding.WriteString(`<multiRef id="id40">value</multiRef>`)
+	testCases = append(testCases, struct {
+		description   string
+		body          string
+		expectedError string
+	}{
+		description:   "case: exponential expansion",
+		body:          expanding.String(),
+		expectedError: `error resolving multi-reference values: multi-reference values expand to more than 33554432 bytes`,
+	})
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.description, func(t *testing.T) {
+			t.Parallel()
+			var res struct {
+				Order Order `xml:"getOrderReturn"`
+			}
+			err := (&Response{Body: []byte(testCase.body)}).Unmarshal(&res)
+			if testCase.expectedError != "" {
+				assert.EqualError(t, err, testCase.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "42", res.Order.ID)
+			assert.Equal(t, "ACME & Co", res.Order.Customer.Name)
+			assert.Len(t, res.Order.Items, 2)
+			for _, item := range res.Order.Items {
+				assert.Equal(t, "X-1", item.SKU)
+			}
+		})
+	}
+}
+
+func TestResponseMetadata(t *testing.T) {
+	t.Parallel()
+	envelope := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><GetOrderResponse xmlns="http://example.com/order"><OrderID>1</OrderID></GetOrderResponse></soap:Body></soap:Envelope>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Header().Set("X-Correlation-ID", "42")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(w, envelope)
+	}))
+	t.Cleanup(server.Close)
+	client := newTestClient(t, "./testdata/order.wsdl", server, Config{})
+
+	before := time.Now()
+	res, err := client.Call(context.Background(), "GetOrder", Params{"OrderID": 1})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, "202 Accepted", res.Status)
+	assert.Equal(t, "42", res.HTTPHeader.Get("X-Correlation-ID"))
+	assert.Equal(t, "text/xml; charset=utf-8", res.ContentType)
+	assert.Equal(t, envelope, string(res.Envelope))
+	assert.False(t, res.Sent.Before(before))
+	assert.Positive(t, res.Duration)
+	cookies := res.Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "abc", cookies[0].Value)
+}
+
+// timingLogger records when the request was logged and when logging the response started
+type timingLogger struct {
+	requestLogged, responseLogging time.Time
+}
+
+func (l *timingLogger) LogRequest(string, http.Header, []byte) {
+	time.Sleep(10 * time.Millisecond)
+	l.requestLogged = time.Now()
+}
+
+func (l *timingLogger) LogResponse(string, http.Header, []byte) {
+	l.responseLogging = time.Now()
+	time.Sleep(10 * time.Millisecond)
+}
+
+func TestResponseDuration(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><GetOrderResponse xmlns="http://example.com/order"><OrderID>1</OrderID></GetOrderResponse></soap:Body></soap:Envelope>`)
+	}))
+	t.Cleanup(server.Close)
+	logger := &timingLogger{}
+	client := newTestClient(t, "./testdata/order.wsdl", server, Config{LogRequests: true, Logger: logger})
+
+	res, err := client.Call(context.Background(), "GetOrder", Params{"OrderID": 1})
+	require.NoError(t, err)
+	assert.False(t, res.Sent.Before(logger.requestLogged), "logging the request isn't part of the duration")
+	assert.False(t, res.Sent.Add(res.Duration).After(logger.responseLogging), "logging and decoding the response aren't part of the duration")
+	assert.Positive(t, res.Duration)
+}
+
+func TestPrepareBody(t *testing.T) {
+	t.Parallel()
+	inherited := namespaces{
+		{Name: xml.Name{Space: "xmlns", Local: "xsi"}, Value: xsiNS},
+		{Name: xml.Name{Space: "xmlns", Local: "tns"}, Value: "urn:shop"},
+	}
+	unchanged := []string{
+		`<Item type="book"><Title>Go</Title></Item>`,
+		`<Item xmlns:t="urn:shop" xsi:type="t:Book"><Title>Go</Title></Item>`,
+		`<Item xsi:type="xsd:string">Go</Item>`,
+	}
+	for _, content := range unchanged {
+		prepared, err := prepareBody([]byte(content), inherited, false)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(prepared))
+	}
+
+	prepared, err := prepareBody([]byte(`<Item xsi:type = "tns:Book"><Title>Go</Title></Item>`), inherited, false)
+	require.NoError(t, err)
+	assert.Contains(t, string(prepared), `xmlns:tns="urn:shop"`)
+}
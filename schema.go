@@ -0,0 +1,394 @@
+package gosoap
+
+import (
+	"encoding/xml"
+	"strconv"
+)
+
+// Schema is a read-only description of the global components of the XML schemas of a WSDL.
+// Named types are referenced by name, look them up with ComplexType and SimpleType.
+// see https://www.w3.org/TR/xmlschema-1/
+type Schema struct {
+	Elements     []*Element
+	ComplexTypes []*ComplexType
+	SimpleTypes  []*SimpleType
+	Attributes   []*Attribute
+}
+
+// Element returns the global element with the given name or nil
+func (s *Schema) Element(name xml.Name) *Element {
+	for _, e := range s.Elements {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// ComplexType returns the global complex type with the given name or nil
+func (s *Schema) ComplexType(name xml.Name) *ComplexType {
+	for _, ct := range s.ComplexTypes {
+		if ct.Name == name {
+			return ct
+		}
+	}
+	return nil
+}
+
+// SimpleType returns the global simple type with the given name or nil
+func (s *Schema) SimpleType(name xml.Name) *SimpleType {
+	for _, st := range s.SimpleTypes {
+		if st.Name == name {
+			return st
+		}
+	}
+	return nil
+}
+
+// Unbounded is the MaxOccurs of particles that can repeat without limit
+const Unbounded = -1
+
+type Element struct {
+	Name xml.Name
+	// Type is the name of the type of the element, it is empty for anonymous types
+	Type xml.Name
+	// Ref is set for particles referencing a global element
+	Ref       bool
+	MinOccurs int
+	MaxOccurs int
+	Nillable  bool
+	Abstract  bool
+	// SubstitutionGroup is the head of the substitution group the element is a member of
+	SubstitutionGroup xml.Name
+	Default           string
+	Fixed             string
+	// ComplexType or SimpleType is the anonymous type of the element
+	ComplexType *ComplexType
+	SimpleType  *SimpleType
+}
+
+type ComplexType struct {
+	// Name is empty for anonymous types
+	Name     xml.Name
+	Abstract bool
+	Mixed    bool
+	// Base is the type the type is derived from by Derivation, "extension" or "restriction"
+	Base          xml.Name
+	Derivation    string
+	SimpleContent bool
+	// Content is the content model declared by the type itself, without the content inherited
+	// from the base type. References to named groups are expanded.
+	Content *ModelGroup
+	// Attributes declared by the type itself, references to attribute groups are expanded
+	Attributes   []*Attribute
+	AnyAttribute bool
+}
+
+// ModelGroup is a sequence, choice or all
+type ModelGroup struct {
+	Kind      string
+	MinOccurs int
+	MaxOccurs int
+	// Ref is the name of the named group the model group was expanded from
+	Ref       xml.Name
+	Particles []*Particle
+}
+
+// Particle holds exactly one of its fields
+type Particle struct {
+	Element *Element
+	Group   *ModelGroup
+	Any     *Wildcard
+}
+
+// Wildcard allows elements of other namespaces
+type Wildcard struct {
+	Namespace       string
+	ProcessContents string
+	MinOccurs       int
+	MaxOccurs       int
+}
+
+type Attribute struct {
+	Name     xml.Name
+	Type     xml.Name
+	Required bool
+	Default  string
+	Fixed    string
+	// SimpleType is the anonymous type of the attribute
+	SimpleType *SimpleType
+}
+
+type SimpleType struct {
+	// Name is empty for anonymous types
+	Name xml.Name
+	// Variety is "atomic", "list" or "union"
+	Variety string
+	// Base is the restricted type of atomic types, BaseType is set instead for an anonymous base type
+	Base     xml.Name
+	BaseType *SimpleType
+	// ItemType is the item type of list types, ItemSimpleType is set instead for an anonymous item type
+	ItemType       xml.Name
+	ItemSimpleType *SimpleType
+	// MemberTypes and MemberSimpleTypes are the named and anonymous member types of union types
+	MemberTypes       []xml.Name
+	MemberSimpleTypes []*SimpleType
+	Facets            Facets
+}
+
+// Facets constrain the values of simple types, unset facets are nil or empty
+// see https://www.w3.org/TR/xmlschema-2/#rf-facets
+type Facets struct {
+	Enumeration    []string
+	Pattern        []string
+	Length         *int
+	MinLength      *int
+	MaxLength      *int
+	MinInclusive   string
+	MaxInclusive   string
+	MinExclusive   string
+	MaxExclusive   string
+	TotalDigits    *int
+	FractionDigits *int
+	WhiteSpace     string
+}
+
+// schemaBuilder converts the schema model. Complex types are converted once, so recursive
+// element references share the same ComplexType.
+type schemaBuilder struct {
+	set             *xsdSchemaSet
+	complexTypes    map[*xsdComplexType]*ComplexType
+	groups          map[*xsdGroup]bool
+	attributeGroups map[*xsdAttributeGroup]bool
+}
+
+func newSchema(set *xsdSchemaSet) *Schema {
+	s := &Schema{}
+	if set == nil {
+		return s
+	}
+	b := &schemaBuilder{
+		set:             set,
+		complexTypes:    map[*xsdComplexType]*ComplexType{},
+		groups:          map[*xsdGroup]bool{},
+		attributeGroups: map[*xsdAttributeGroup]bool{},
+	}
+	for _, schema := range set.schemas {
+		for _, e := range schema.Elements {
+			element := &Element{MinOccurs: 1, MaxOccurs: 1}
+			b.fillElement(element, e)
+			s.Elements = append(s.Elements, element)
+		}
+		for _, ct := range schema.ComplexTypes {
+			s.ComplexTypes = append(s.ComplexTypes, b.complexType(ct))
+		}
+		for _, st := range schema.SimpleTypes {
+			s.SimpleTypes = append(s.SimpleTypes, b.simpleType(st))
+		}
+		for _, a := range schema.Attributes {
+			s.Attributes = append(s.Attributes, b.attribute(a))
+		}
+	}
+	return s
+}
+
+func (b *schemaBuilder) fillElement(element *Element, e *xsdElement) {
+	element.Name = e.QName
+	element.Type = e.TypeName
+	element.Nillable = e.Nillable
+	element.Abstract = e.Abstract
+	element.SubstitutionGroup = e.SubstitutionGroupName
+	element.Default = e.Default
+	element.Fixed = e.Fixed
+	if e.ComplexType != nil {
+		element.ComplexType = b.complexType(e.ComplexType)
+	}
+	if e.SimpleType != nil {
+		element.SimpleType = b.simpleType(e.SimpleType)
+	}
+}
+
+// particleElement converts a local element or an element reference of a model group
+func (b *schemaBuilder) particleElement(e *xsdElement) *Element {
+	element := &Element{}
+	decl := b.set.resolveElement(e)
+	b.fillElement(element, decl)
+	element.Ref = decl != e
+	element.MinOccurs = occurs(e.MinOccurs)
+	element.MaxOccurs = occurs(e.MaxOccurs)
+	return element
+}
+
+func (b *schemaBuilder) complexType(ct *xsdComplexType) *ComplexType {
+	if c, ok := b.complexTypes[ct]; ok {
+		return c
+	}
+	c := &ComplexType{
+		Name:         ct.QName,
+		Abstract:     ct.Abstract,
+		Mixed:        ct.Mixed,
+		AnyAttribute: ct.AnyAttribute != nil,
+	}
+	b.complexTypes[ct] = c
+	c.Content = b.modelGroup(ct.modelGroup())
+	c.Attributes = b.attributes(ct.Attributes, ct.AttributeGroups)
+	if d, extension := ct.derivation(); d != nil {
+		c.Base = d.BaseName
+		c.Derivation = "restriction"
+		if extension {
+			c.Derivation = "extension"
+		}
+		c.SimpleContent = ct.SimpleContent != nil
+		c.Mixed = c.Mixed || (ct.ComplexContent != nil && ct.ComplexContent.Mixed)
+		c.Content = b.modelGroup(d.modelGroup())
+		c.Attributes = append(c.Attributes, b.attributes(d.Attributes, d.AttributeGroups)...)
+		c.AnyAttribute = c.AnyAttribute || d.AnyAttribute != nil
+	}
+	return c
+}
+
+func (b *schemaBuilder) modelGroup(g *xsdModelGroup) *ModelGroup {
+	if g == nil {
+		return nil
+	}
+	group := &ModelGroup{Kind: g.Kind, MinOccurs: occurs(g.MinOccurs), MaxOccurs: occurs(g.MaxOccurs)}
+	for _, p := range g.Particles {
+		switch {
+		case p.Element != nil:
+			group.Particles = append(group.Particles, &Particle{Element: b.particleElement(p.Element)})
+		case p.ModelGroup != nil:
+			group.Particles = append(group.Particles, &Particle{Group: b.modelGroup(p.ModelGroup)})
+		case p.Group != nil:
+			if expanded := b.groupRef(p.Group); expanded != nil {
+				group.Particles = append(group.Particles, &Particle{Group: expanded})
+			}
+		case p.Any != nil:
+			group.Particles = append(group.Particles, &Particle{Any: &Wildcard{
+				Namespace:       p.Any.Namespace,
+				ProcessContents: p.Any.ProcessContents,
+				MinOccurs:       occurs(p.Any.MinOccurs),
+				MaxOccurs:       occurs(p.Any.MaxOccurs),
+			}})
+		}
+	}
+	return group
+}
+
+// groupRef expands a reference to a named group, circular references are dropped
+func (b *schemaBuilder) groupRef(ref *xsdGroup) *ModelGroup {
+	decl := b.set.group(ref)
+	if b.groups[decl] {
+		return nil
+	}
+	b.groups[decl] = true
+	defer delete(b.groups, decl)
+	group := b.modelGroup(decl.modelGroup())
+	if group == nil {
+		return nil
+	}
+	group.Ref = ref.RefName
+	group.MinOccurs = occurs(ref.MinOccurs)
+	group.MaxOccurs = occurs(ref.MaxOccurs)
+	return group
+}
+
+func (b *schemaBuilder) attributes(attributes []*xsdAttribute, groups []*xsdAttributeGroup) []*Attribute {
+	var result []*Attribute
+	for _, a := range attributes {
+		result = append(result, b.attribute(a))
+	}
+	for _, g := range groups {
+		decl := g
+		if g.RefName.Local != "" {
+			decl = b.set.attributeGroups[g.RefName]
+		}
+		if decl == nil || b.attributeGroups[decl] {
+			continue
+		}
+		b.attributeGroups[decl] = true
+		result = append(result, b.attributes(decl.Attributes, decl.AttributeGroups)...)
+		delete(b.attributeGroups, decl)
+	}
+	return result
+}
+
+func (b *schemaBuilder) attribute(a *xsdAttribute) *Attribute {
+	decl := b.set.attribute(a)
+	attribute := &Attribute{
+		Name:     a.QName,
+		Type:     decl.TypeName,
+		Required: a.Use == "required",
+		Default:  decl.Default,
+		Fixed:    decl.Fixed,
+	}
+	if a.Default != "" {
+		attribute.Default = a.Default
+	}
+	if a.Fixed != "" {
+		attribute.Fixed = a.Fixed
+	}
+	if decl.SimpleType != nil {
+		attribute.SimpleType = b.simpleType(decl.SimpleType)
+	}
+	return attribute
+}
+
+func (b *schemaBuilder) simpleType(st *xsdSimpleType) *SimpleType {
+	s := &SimpleType{Name: st.QName}
+	switch {
+	case st.List != nil:
+		s.Variety = "list"
+		s.ItemType = st.List.ItemTypeName
+		if st.List.SimpleType != nil {
+			s.ItemSimpleType = b.simpleType(st.List.SimpleType)
+		}
+	case st.Union != nil:
+		s.Variety = "union"
+		s.MemberTypes = append(s.MemberTypes, st.Union.MemberTypeNames...)
+		for _, member := range st.Union.SimpleTypes {
+			s.MemberSimpleTypes = append(s.MemberSimpleTypes, b.simpleType(member))
+		}
+	default:
+		s.Variety = "atomic"
+		if r := st.Restriction; r != nil {
+			s.Base = r.BaseName
+			if r.SimpleType != nil {
+				s.BaseType = b.simpleType(r.SimpleType)
+			}
+			s.Facets = newFacets(r.Facets)
+		}
+	}
+	return s
+}
+
+func newFacets(f xsdFacets) Facets {
+	value := func(kind string) string {
+		v, _ := f.get(kind)
+		return v
+	}
+	number := func(kind string) *int {
+		v, ok := f.get(kind)
+		if !ok {
+			return nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil
+		}
+		return &n
+	}
+	return Facets{
+		Enumeration:    f.values("enumeration"),
+		Pattern:        f.values("pattern"),
+		Length:         number("length"),
+		MinLength:      number("minLength"),
+		MaxLength:      number("maxLength"),
+		MinInclusive:   value("minInclusive"),
+		MaxInclusive:   value("maxInclusive"),
+		MinExclusive:   value("minExclusive"),
+		MaxExclusive:   value("maxExclusive"),
+		TotalDigits:    number("totalDigits"),
+		FractionDigits: number("fractionDigits"),
+		WhiteSpace:     value("whiteSpace"),
+	}
+}
@@ -0,0 +1,83 @@
+package gosoap
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// snapshots start with a magic string and a format version, snapshots of other versions are rejected
+const (
+	snapshotMagic   = "gosoap-wsdl\x00"
+	snapshotVersion = 1
+)
+
+// Snapshot loads a WSDL and the documents it imports and serializes the compiled definitions into a compact
+// binary snapshot. SourceFromSnapshot restores it without loading and parsing the WSDL again, which makes
+// it suitable for embedding into short-lived programs.
+// Snapshots are only readable by the version of this package that created them.
+func Snapshot(source WSDLSource) ([]byte, error) {
+	d, err := getWSDLDefinitions(context.Background(), source, &Config{})
+	if err != nil {
+		return nil, err
+	}
+	return d.snapshot()
+}
+
+// Snapshot serializes the definitions the client was created from, see Snapshot
+func (c *Client) Snapshot() ([]byte, error) {
+	return c.state().definitions.snapshot()
+}
+
+// SourceFromSnapshot restores definitions serialized by Snapshot
+func SourceFromSnapshot(snapshot []byte) WSDLSource {
+	return documentSource(func(ctx context.Context, config *Config) (*WSDLDocument, error) {
+		d, err := restoreSnapshot(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		return &WSDLDocument{Content: snapshot, compiled: d}, nil
+	})
+}
+
+func (d *wsdlDefinitions) snapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(snapshotMagic)
+	buf.WriteByte(snapshotVersion)
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if err := gob.NewEncoder(w).Encode(d); err != nil {
+		return nil, fmt.Errorf("could not encode WSDL snapshot: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func restoreSnapshot(snapshot []byte) (*wsdlDefinitions, error) {
+	header := len(snapshotMagic) + 1
+	if len(snapshot) < header || string(snapshot[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, errors.New("data is not a WSDL snapshot")
+	}
+	if version := snapshot[header-1]; version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported WSDL snapshot version %d, expected %d", version, snapshotVersion)
+	}
+	r := flate.NewReader(bytes.NewReader(snapshot[header:]))
+	defer r.Close()
+	var d *wsdlDefinitions
+	if err := gob.NewDecoder(r).Decode(&d); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not decode WSDL snapshot: %w", err)
+	}
+	if d == nil {
+		return nil, errors.New("WSDL snapshot is empty")
+	}
+	d.compile()
+	return d, nil
+}
@@ -0,0 +1,82 @@
+package gosoap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+	dir, err := filepath.Abs("testdata/split")
+	require.NoError(t, err)
+	quote, err := os.ReadFile("testdata/quote.wsdl")
+	require.NoError(t, err)
+
+	sources := map[string]WSDLSource{
+		"quote":   SourceFromBytes(quote),
+		"schema":  SourceFromBytes([]byte(schemaTestWSDL)),
+		"wsdl20":  SourceFromURI("file://" + filepath.Join(dir, "../quote20.wsdl")),
+		"imports": SourceFromURI("file://" + filepath.Join(dir, "main.wsdl")),
+	}
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			snapshot, err := Snapshot(source)
+			require.NoError(t, err)
+
+			original, err := ParseWSDL(source)
+			require.NoError(t, err)
+			restored, err := ParseWSDL(SourceFromSnapshot(snapshot))
+			require.NoError(t, err)
+			assert.Equal(t, original, restored)
+		})
+	}
+}
+
+func TestSnapshotClient(t *testing.T) {
+	t.Parallel()
+	quote, err := os.ReadFile("testdata/quote.wsdl")
+	require.NoError(t, err)
+	snapshot, err := Snapshot(SourceFromBytes(quote))
+	require.NoError(t, err)
+
+	server, reqBody := newCaptureServer(t)
+	client, err := NewClientWithContext(context.Background(), SourceFromSnapshot(snapshot), &Config{Client: server.Client()})
+	require.NoError(t, err)
+	setAddress(client, server.URL)
+
+	_, err = client.Call(context.Background(), "Convert", Params{"symbol": "ACME", "currency": "EUR"})
+	require.NoError(t, err)
+	assert.Contains(t, string(*reqBody), `<Symbol xmlns="http://example.com/quote">ACME</Symbol>`)
+
+	again, err := client.Snapshot()
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, again)
+}
+
+func TestSnapshotInvalid(t *testing.T) {
+	t.Parallel()
+	valid, err := Snapshot(SourceFromBytes([]byte(schemaTestWSDL)))
+	require.NoError(t, err)
+	outdated := append([]byte{}, valid...)
+	outdated[len(snapshotMagic)] = snapshotVersion + 1
+
+	cases := map[string]struct {
+		snapshot []byte
+		err      string
+	}{
+		"not a snapshot": {snapshot: []byte("<definitions/>"), err: "could not load WSDL: data is not a WSDL snapshot"},
+		"version":        {snapshot: outdated, err: "could not load WSDL: unsupported WSDL snapshot version 2, expected 1"},
+		"truncated":      {snapshot: valid[:len(valid)/2], err: "could not load WSDL: could not decode WSDL snapshot: unexpected EOF"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseWSDL(SourceFromSnapshot(tc.snapshot))
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/net/html/charset"
 )
@@ -37,10 +39,61 @@ type Config struct {
 
 	Username string
 	Password string
+
+	// WSDLCacheDir is a directory where WSDL and schema documents fetched over http(s) are cached.
+	// Cached documents are revalidated with their ETag or Last-Modified date and used instead
+	// if the server is unreachable or fails.
+	WSDLCacheDir string
+
+	// RefreshInterval is the interval in which the WSDL is loaded again in the background,
+	// the client switches to the new binding and endpoint without interrupting requests in flight.
+	RefreshInterval time.Duration
+	// RefreshOn reports whether an error returned by Do should trigger a refresh of the WSDL,
+	// e.g. because the server rejected an outdated SOAPAction
+	RefreshOn func(err error) bool
+	// OnRefresh is called after a background refresh with the changes, it isn't called if nothing changed.
+	// err is set if the WSDL couldn't be loaded, the client keeps using the previous WSDL in that case.
+	OnRefresh func(change *WSDLChange, err error)
+
+	// ValidateRequests checks request bodies against the schema of the input message before they are sent,
+	// Do returns a *ValidationError listing every violation instead of sending an invalid request
+	ValidateRequests bool
+	// ValidateResponses checks response bodies against the schema of the output message,
+	// Do returns the response together with a *ValidationError if it doesn't match
+	ValidateResponses bool
+	// OnResponseViolations receives the violations of invalid responses instead of Do failing,
+	// e.g. to monitor services that change their responses without notice
+	OnResponseViolations func(operation string, err *ValidationError)
+
+	// HeaderProcessors are called by Do with the response header blocks of their name that target the client.
+	// A response with a mandatory header block that targets the client and has no processor fails with a *MustUnderstandError.
+	HeaderProcessors map[xml.Name]HeaderProcessor
+	// Roles are the URIs of the SOAP 1.2 roles or SOAP 1.1 actors the client acts in,
+	// in addition to the next and ultimate receiver roles
+	Roles []string
+	// IgnoreMustUnderstand disables the check for mandatory header blocks without processor
+	IgnoreMustUnderstand bool
+	// IgnoreMissingHeaders sends requests without the header blocks the binding declares for the input,
+	// by default Do fails before sending such a request
+	IgnoreMissingHeaders bool
+
+	// Types resolves the xsi:type and element names of Polymorphic values in requests and responses,
+	// nil uses the types registered with RegisterType and RegisterElement
+	Types *TypeRegistry
+
+	// load is set while the WSDL source is called by the client
+	load *sourceLoad
 }
 
-// NewClient return new *Client to handle the requests with the WSDL
+// NewClient return new *Client to handle the requests with the WSDL.
+// Requests are sent as SOAP 1.1 envelopes, ports of WSDL 2.0 documents can only be used if their binding
+// declares wsoap:version="1.1".
 func NewClient(wsdlSource WSDLSource, config *Config) (*Client, error) {
+	return NewClientWithContext(context.Background(), wsdlSource, config)
+}
+
+// NewClientWithContext is like NewClient, ctx is used to load the WSDL and the documents it imports
+func NewClientWithContext(ctx context.Context, wsdlSource WSDLSource, config *Config) (*Client, error) {
 	if config == nil {
 		config = &Config{}
 	}
@@ -65,11 +118,39 @@ func NewClient(wsdlSource WSDLSource, config *Config) (*Client, error) {
 		}
 	}
 
-	definitions, err := getWSDLDefinitions(wsdlSource, config)
+	definitions, err := getWSDLDefinitions(ctx, wsdlSource, config)
+	if err != nil {
+		return nil, err
+	}
+	state, err := newWSDLState(definitions, config)
 	if err != nil {
 		return nil, err
 	}
+	config.Service = state.service
+	config.Port = state.port
+
+	c := &Client{
+		config:     *config,
+		httpClient: config.Client,
+		source:     wsdlSource,
+	}
+	c.wsdl.Store(state)
+	c.startRefresher()
+	return c, nil
+}
+
+// wsdlState is the part of a client derived from the WSDL, it is replaced as a whole when the WSDL is refreshed
+type wsdlState struct {
+	service       string
+	port          string
+	address       string
+	namespace     string
+	autoActionURL string
+	binding       *wsdlBinding
+	definitions   *wsdlDefinitions
+}
 
+func newWSDLState(definitions *wsdlDefinitions, config *Config) (*wsdlState, error) {
 	var namespace string
 	if definitions.Types != nil {
 		// FIXME: this can be incorrect, we need to add a config option to select a type element (by targetNamespace?)
@@ -84,28 +165,16 @@ func NewClient(wsdlSource WSDLSource, config *Config) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not determin SOAP address: %w", err)
 	}
-	config.Service = service.Name
-	config.Port = port.Name
 
-	bindingName := port.Binding
-	splitBindingName := strings.Split(port.Binding, ":")
-	if len(splitBindingName) == 2 {
-		bindingName = splitBindingName[1] // strip off the namespace
-	}
-	var binding *wsdlBinding
-	for _, b := range definitions.Bindings {
-		if b.Name == bindingName || b.Name == port.Binding {
-			binding = b
-			break
-		}
-	}
+	binding := definitions.binding(port.BindingName)
 	if binding == nil {
 		return nil, fmt.Errorf("could not find binding matching %q", port.Binding)
 	}
-	return &Client{
-		config:        *config,
-		httpClient:    config.Client,
+	return &wsdlState{
+		service:       service.Name,
+		port:          port.Name,
 		binding:       binding,
+		definitions:   definitions,
 		autoActionURL: strings.TrimSuffix(definitions.TargetNamespace, "/"),
 		address:       port.SoapAddresses[0].Location, // TODO: use multiple addresses?
 		namespace:     namespace,
@@ -151,6 +220,9 @@ func (d *wsdlDefinitions) serviceAndPort(serviceName, portName string) (*wsdlSer
 			return nil, nil, fmt.Errorf("no port matching %q found, possible values are %v", portName, possiblePorts)
 		}
 	}
+	if len(port.SoapAddresses) == 0 && port.Unsupported != "" {
+		return nil, nil, fmt.Errorf("WSDL port %q can't be used: %s", port.Name, port.Unsupported)
+	}
 	if len(port.SoapAddresses) == 0 {
 		return nil, nil, fmt.Errorf("WSDL port %q has no addresses", port.Name)
 	}
@@ -162,32 +234,88 @@ func (d *wsdlDefinitions) serviceAndPort(serviceName, portName string) (*wsdlSer
 type Client struct {
 	httpClient *http.Client
 	config     Config
+	source     WSDLSource
 
-	address       string
-	namespace     string
-	autoActionURL string
-	binding       *wsdlBinding
+	// wsdl is swapped atomically when the WSDL is refreshed, a request uses the state it started with
+	wsdl      atomic.Pointer[wsdlState]
+	refresher *refresher
 }
 
+// state returns the current WSDL state, the zero state if the client wasn't created by NewClient
+func (c *Client) state() *wsdlState {
+	if s := c.wsdl.Load(); s != nil {
+		return s
+	}
+	return &wsdlState{}
+}
+
+// Call calls an operation, headerParams are the header blocks of the request.
+// A CallOption passed among the header blocks is applied to the request instead.
 func (c *Client) Call(ctx context.Context, wsdlOperation string, body any, headerParams ...any) (res *Response, err error) {
-	return c.Do(ctx, NewRequest(wsdlOperation, body, headerParams...))
+	var headers []any
+	var opts []CallOption
+	for _, h := range headerParams {
+		if opt, ok := h.(CallOption); ok {
+			opts = append(opts, opt)
+		} else {
+			headers = append(headers, h)
+		}
+	}
+	return c.Do(ctx, NewRequest(wsdlOperation, body, headers...), opts...)
+}
+
+// Do Process Soap Request, opts are applied to a copy of req
+func (c *Client) Do(ctx context.Context, req *Request, opts ...CallOption) (res *Response, err error) {
+	if len(opts) > 0 {
+		r := *req
+		for _, opt := range opts {
+			opt(&r)
+		}
+		req = &r
+	}
+	res, err = c.do(ctx, req)
+	if err != nil && c.config.RefreshOn != nil && c.config.RefreshOn(err) {
+		c.triggerRefresh()
+	}
+	return res, err
 }
 
-// Do Process Soap Request
-func (c *Client) Do(ctx context.Context, req *Request) (res *Response, err error) {
+func (c *Client) do(ctx context.Context, req *Request) (res *Response, err error) {
+	s := c.state()
+	op, err := s.binding.operation(req.WSDLOperation, req.InputName, req.OutputName)
+	if err != nil {
+		return nil, err
+	}
 	var action string
-	if c.config.AutoAction {
-		action = fmt.Sprintf("%s/%s/%s", c.autoActionURL, c.config.Service, req.WSDLOperation)
-	} else {
-		action, err = c.binding.GetSoapActionFromWsdlOperation(req.WSDLOperation)
+	switch {
+	case req.SOAPAction != "":
+		action = req.SOAPAction
+	case c.config.AutoAction:
+		action = fmt.Sprintf("%s/%s/%s", s.autoActionURL, c.config.Service, req.WSDLOperation)
+	default:
+		action, err = s.binding.soapAction(op, req.WSDLOperation)
 		if err != nil {
 			return nil, err
 		}
 	}
+	layout, err := s.bodyLayout(req, op)
+	if err != nil {
+		return nil, err
+	}
+	if !c.config.IgnoreMissingHeaders {
+		if err := s.checkHeaders(req, op); err != nil {
+			return nil, err
+		}
+	}
+	address := s.address
+	if req.Endpoint != "" {
+		address = req.Endpoint
+	}
 	p := &process{
 		config:     &c.config,
-		namespace:  c.namespace,
+		address:    address,
 		request:    req,
+		layout:     layout,
 		soapAction: action,
 	}
 
@@ -195,8 +323,18 @@ func (c *Client) Do(ctx context.Context, req *Request) (res *Response, err error
 	if err != nil {
 		return nil, err
 	}
+	if c.config.ValidateRequests {
+		if err := s.validateRequest(p.payload, op); err != nil {
+			return nil, fmt.Errorf("invalid request for operation %q: %w", req.WSDLOperation, err)
+		}
+	}
 
-	b, err := c.doRequest(ctx, p)
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+	}
+	httpRes, b, err := c.doRequest(ctx, p)
 	if err != nil {
 		return nil, ErrorWithPayload{err, p.payload}
 	}
@@ -212,44 +350,181 @@ func (c *Client) Do(ctx context.Context, req *Request) (res *Response, err error
 	err = decoder.Decode(&soap)
 
 	res = &Response{
-		Body:          soap.Body.Contents,
-		HeaderEntries: soap.Header.Contents,
+		Body:             soap.Body.Contents,
+		HeaderEntries:    soap.Header.Contents,
+		bodyNamespaces:   append(append(namespaces{}, soap.Attrs...), soap.Body.Attrs...),
+		headerNamespaces: append(append(namespaces{}, soap.Attrs...), soap.Header.Attrs...),
+		envelopeNS:       soap.XMLName.Space,
+		StatusCode:       httpRes.StatusCode,
+		Status:           httpRes.Status,
+		HTTPHeader:       httpRes.Header,
+		ContentType:      httpRes.Header.Get("Content-Type"),
+		Sent:             p.sent,
+		Duration:         p.received.Sub(p.sent),
+		Envelope:         b,
+		wsdl:             s,
+		operation:        op,
+		types:            c.config.Types,
 	}
 	if err != nil {
 		return res, ErrorWithPayload{err, p.payload}
 	}
+	if err := c.processHeaders(ctx, res); err != nil {
+		return res, fmt.Errorf("invalid response header for operation %q: %w", req.WSDLOperation, err)
+	}
+	if c.config.ValidateResponses {
+		if verr := s.validateResponse(res, op); verr != nil {
+			if c.config.OnResponseViolations == nil {
+				return res, fmt.Errorf("invalid response for operation %q: %w", req.WSDLOperation, verr)
+			}
+			c.config.OnResponseViolations(req.WSDLOperation, verr)
+		}
+	}
 
 	return res, nil
 }
 
+// bodyLayout derives the elements the request body is wrapped in from the input message of the operation.
+// Document style operations use the elements referenced by the message parts, a body that already marshals
+// itself as the element of a single part message is not wrapped again (bare style).
+// RPC style operations wrap the part accessors in an element named after the operation.
+// If the message can't be resolved the body is wrapped in an element named after the operation.
+// op is the binding operation selected by the request, nil if the binding has none.
+func (s *wsdlState) bodyLayout(req *Request, op *wsdlOperation) (bodyLayout, error) {
+	if req.WSDLOperation == "" {
+		return bodyLayout{}, fmt.Errorf("operation is empty")
+	}
+	fallback := bodyLayout{wrapper: xml.Name{Space: s.namespace, Local: req.WSDLOperation}}
+	if s.namespace == "" {
+		return bodyLayout{}, fmt.Errorf("namespace is empty")
+	}
+	if s.definitions == nil {
+		return fallback, nil
+	}
+	body := inputBody(op)
+	if body != nil && body.Use == "encoded" {
+		fallback.encodingStyle = body.EncodingStyle
+		if fallback.encodingStyle == "" {
+			fallback.encodingStyle = soapEncodingNS
+		}
+	}
+	msg, err := s.definitions.inputMessage(s.binding, op)
+
+	if s.binding.style(op) == "rpc" {
+		layout := fallback
+		layout.rpc = true
+		if body != nil && body.Namespace != "" {
+			layout.wrapper.Space = body.Namespace
+		}
+		if err == nil {
+			for _, part := range msg.Parts {
+				if !body.includes(part.Name) {
+					continue
+				}
+				if err := part.checkNames(); err != nil {
+					return bodyLayout{}, err
+				}
+				p := bodyPart{name: part.Name}
+				if part.Type != "" {
+					p.typ = part.TypeName
+				}
+				layout.parts = append(layout.parts, p)
+			}
+		}
+		return layout, nil
+	}
+	if err != nil {
+		return fallback, nil
+	}
+
+	parts := make([]bodyPart, 0, len(msg.Parts))
+	for _, part := range msg.Parts {
+		if !body.includes(part.Name) {
+			continue
+		}
+		if err := part.checkNames(); err != nil {
+			return bodyLayout{}, err
+		}
+		if part.Element == "" {
+			// type parts can't be represented in document style
+			return fallback, nil
+		}
+		parts = append(parts, bodyPart{name: part.Name, element: part.ElementName})
+	}
+	layout := bodyLayout{encodingStyle: fallback.encodingStyle}
+	switch len(parts) {
+	case 0:
+	case 1:
+		if name, ok := elementName(req.Body); ok && name.Local == parts[0].element.Local {
+			break
+		}
+		layout.wrapper = parts[0].element
+	default:
+		layout.parts = parts
+	}
+	return layout, nil
+}
+
+// checkHeaders makes sure every header block the binding declares for the input of an operation
+// is present in the request.
+func (s *wsdlState) checkHeaders(req *Request, op *wsdlOperation) error {
+	if s.definitions == nil {
+		return nil
+	}
+	present := headerEntryNames(req.HeaderEntries)
+	for _, h := range inputHeaders(op) {
+		name, err := s.definitions.headerName(h)
+		if err != nil {
+			return err
+		}
+		if !containsName(present, name) {
+			return fmt.Errorf("operation %q requires the SOAP header %q which is missing from the request", req.WSDLOperation, name.Local)
+		}
+	}
+	return nil
+}
+
 type process struct {
-	config    *Config
-	request   *Request
-	namespace string
+	config  *Config
+	address string
+	request *Request
+	layout  bodyLayout
 	// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383528
 	soapAction string
 	payload    []byte
+	// sent is the time the request was sent, received the time the response body was read
+	sent     time.Time
+	received time.Time
 }
 
 // doRequest makes new request to the server using the c.Method, c.URL and the body.
-// body is enveloped in Do method
-func (c *Client) doRequest(ctx context.Context, p *process) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.address, bytes.NewBuffer(p.payload))
+// body is enveloped in Do method, the returned HTTP response is closed and its body is returned separately.
+// The times the request was sent and the response body was read are recorded in p.
+func (c *Client) doRequest(ctx context.Context, p *process) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.address, bytes.NewBuffer(p.payload))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if c.config.LogRequests {
+	logRequests := c.config.LogRequests
+	if p.request.LogRequests != nil {
+		logRequests = *p.request.LogRequests
+	}
+	if logRequests {
 		var body []byte
 		req.Body, body, err = drainBody(req.Body)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		c.config.Logger.LogRequest(p.request.WSDLOperation, req.Header, body)
 	}
 
-	if c.config.Username != "" && c.config.Password != "" {
-		req.SetBasicAuth(c.config.Username, c.config.Password)
+	username, password := c.config.Username, c.config.Password
+	if p.request.Username != "" {
+		username, password = p.request.Username, p.request.Password
+	}
+	if username != "" && password != "" {
+		req.SetBasicAuth(username, password)
 	}
 
 	req.ContentLength = int64(len(p.payload))
@@ -259,23 +534,30 @@ func (c *Client) doRequest(ctx context.Context, p *process) ([]byte, error) {
 	if p.soapAction != "" {
 		req.Header.Add("SOAPAction", p.soapAction)
 	}
+	for key, values := range p.request.HTTPHeader {
+		req.Header.Del(key)
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
 
+	p.sent = time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
-	if c.config.LogRequests {
-		var body []byte
-		resp.Body, body, err = drainBody(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-		c.config.Logger.LogResponse(p.request.WSDLOperation, req.Header, body)
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
 	}
+	p.received = time.Now()
 
-	return io.ReadAll(resp.Body)
+	if logRequests {
+		c.config.Logger.LogResponse(p.request.WSDLOperation, req.Header, b)
+	}
+	return resp, b, nil
 }
 
 // from net/http/httputil
@@ -310,19 +592,26 @@ func GetPayloadFromError(err error) []byte {
 
 // SoapEnvelope struct
 type SoapEnvelope struct {
-	XMLName struct{} `xml:"Envelope"`
+	// XMLName.Space is the namespace of the envelope, which identifies the SOAP version
+	XMLName xml.Name `xml:"Envelope"`
 	Header  SoapHeader
 	Body    SoapBody
+	// namespace declarations of the envelope
+	Attrs []xml.Attr `xml:",any,attr"`
 }
 
 // SoapHeader struct
 type SoapHeader struct {
 	XMLName  struct{} `xml:"Header"`
 	Contents []byte   `xml:",innerxml"`
+	// namespace declarations of the header
+	Attrs []xml.Attr `xml:",any,attr"`
 }
 
 // SoapBody struct
 type SoapBody struct {
 	XMLName  struct{} `xml:"Body"`
 	Contents []byte   `xml:",innerxml"`
+	// namespace declarations of the body
+	Attrs []xml.Attr `xml:",any,attr"`
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -178,3 +179,66 @@ func TestClient_Call_NonUtf8(t *testing.T) {
 	_, err = soap.Call(context.Background(), "login", Params{"client": "demo", "username": "robert", "password": "iliasdemo"})
 	assert.NoError(t, err)
 }
+
+type recordingLogger struct {
+	requests int
+}
+
+func (l *recordingLogger) LogRequest(string, http.Header, []byte) {
+	l.requests++
+}
+
+func (l *recordingLogger) LogResponse(string, http.Header, []byte) {}
+
+func TestCallOptions(t *testing.T) {
+	t.Parallel()
+	var received *http.Request
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Delay") != "" {
+				time.Sleep(100 * time.Millisecond)
+			}
+			received = r
+			w.Header().Set("X-Server", name)
+			_, _ = w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body/></soap:Envelope>`))
+		}
+	}
+	server := httptest.NewServer(handler("default"))
+	t.Cleanup(server.Close)
+	other := httptest.NewServer(handler("other"))
+	t.Cleanup(other.Close)
+	logger := &recordingLogger{}
+	client := newTestClient(t, "./testdata/order.wsdl", server, Config{Username: "user", Password: "secret", Logger: logger})
+	ctx := context.Background()
+
+	_, err := client.Call(ctx, "GetOrder", Params{"OrderID": 1})
+	require.NoError(t, err)
+	assert.Equal(t, server.Listener.Addr().String(), received.Host)
+	assert.Equal(t, "http://example.com/order/GetOrder", received.Header.Get("SOAPAction"))
+	username, _, _ := received.BasicAuth()
+	assert.Equal(t, "user", username)
+	assert.Zero(t, logger.requests)
+
+	_, err = client.Call(ctx, "GetOrder", Params{"OrderID": 1},
+		WithEndpoint(other.URL),
+		WithSOAPAction("urn:custom"),
+		WithHTTPHeader("X-Correlation-ID", "42"),
+		WithHTTPHeader("Content-Type", "application/soap+xml"),
+		WithBasicAuth("admin", "hunter2"),
+		WithLogging(true),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, other.Listener.Addr().String(), received.Host)
+	assert.Equal(t, "urn:custom", received.Header.Get("SOAPAction"))
+	assert.Equal(t, "42", received.Header.Get("X-Correlation-ID"))
+	assert.Equal(t, []string{"application/soap+xml"}, received.Header.Values("Content-Type"))
+	username, password, _ := received.BasicAuth()
+	assert.Equal(t, "admin", username)
+	assert.Equal(t, "hunter2", password)
+	assert.Equal(t, 1, logger.requests)
+
+	req := NewRequest("GetOrder", Params{"OrderID": 1})
+	_, err = client.Do(ctx, req, WithTimeout(10*time.Millisecond), WithHTTPHeader("X-Delay", "1"))
+	assert.ErrorContains(t, err, context.DeadlineExceeded.Error())
+	assert.Empty(t, req.HTTPHeader, "options must not change the request")
+}
@@ -0,0 +1,338 @@
+package gosoap
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SoMuchForSubtlety/gosoap/xsd"
+)
+
+const (
+	// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383495
+	soapEncodingNS = "http://schemas.xmlsoap.org/soap/encoding/"
+	xsdNS          = "http://www.w3.org/2001/XMLSchema"
+	xsiNS          = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// prefixes used for the xsi:type and arrayType values written by the SOAP encoder
+var encodingPrefixes = map[string]string{
+	xsdNS:          "xsd",
+	xsiNS:          "xsi",
+	soapEncodingNS: "SOAP-ENC",
+}
+
+// encodingAttrs returns the attributes declaring the encoding style of an RPC/encoded wrapper element
+// and the namespaces needed for the xsi:type attributes inside it.
+func encodingAttrs(encodingStyle string, c *Config) []xml.Attr {
+	attrs := []xml.Attr{
+		{Name: xml.Name{Space: "", Local: fmt.Sprintf("%s:encodingStyle", c.EnvelopePrefix)}, Value: encodingStyle},
+	}
+	namespaces := []string{soapEncodingNS, xsdNS, xsiNS}
+	for _, ns := range namespaces {
+		decl := "xmlns:" + encodingPrefixes[ns]
+		if c.EnvelopeAttrs[decl] == ns {
+			continue
+		}
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Space: "", Local: decl}, Value: ns})
+	}
+	return attrs
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	byteSliceType  = reflect.TypeOf([]byte{})
+	arrayParamType = reflect.TypeOf(ArrayParams{})
+	marshalerType  = reflect.TypeOf((*xml.Marshaler)(nil)).Elem()
+)
+
+// libraryTypes are the types of the xsd package
+var libraryTypes = map[reflect.Type]string{
+	reflect.TypeOf(xsd.DateTime{}):     "dateTime",
+	reflect.TypeOf(xsd.Date{}):         "date",
+	reflect.TypeOf(xsd.Time{}):         "time",
+	reflect.TypeOf(xsd.Duration{}):     "duration",
+	reflect.TypeOf(xsd.Decimal{}):      "decimal",
+	reflect.TypeOf(xsd.Base64Binary{}): "base64Binary",
+	reflect.TypeOf(xsd.HexBinary{}):    "hexBinary",
+}
+
+// xsdTypeOf maps a Go type to the XSD built-in type used for its xsi:type
+func xsdTypeOf(t reflect.Type) (xml.Name, bool) {
+	name := func(local string) (xml.Name, bool) {
+		return xml.Name{Space: xsdNS, Local: local}, true
+	}
+	switch t {
+	case timeType:
+		return name("dateTime")
+	case byteSliceType:
+		return name("base64Binary")
+	}
+	if local, ok := libraryTypes[t]; ok {
+		return name(local)
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return name("string")
+	case reflect.Bool:
+		return name("boolean")
+	case reflect.Int, reflect.Int32:
+		return name("int")
+	case reflect.Int8:
+		return name("byte")
+	case reflect.Int16:
+		return name("short")
+	case reflect.Int64:
+		return name("long")
+	case reflect.Uint, reflect.Uint32:
+		return name("unsignedInt")
+	case reflect.Uint8:
+		return name("unsignedByte")
+	case reflect.Uint16:
+		return name("unsignedShort")
+	case reflect.Uint64:
+		return name("unsignedLong")
+	case reflect.Float32:
+		return name("float")
+	case reflect.Float64:
+		return name("double")
+	}
+	return xml.Name{}, false
+}
+
+// namespacePrefixes allocates the prefixes of QName values within an envelope. Every namespace gets its own prefix,
+// so nested elements never declare the same prefix for different namespaces. A nil map always uses ns1.
+type namespacePrefixes map[string]string
+
+func (p namespacePrefixes) prefix(space string) string {
+	if p == nil {
+		return "ns1"
+	}
+	prefix, ok := p[space]
+	if !ok {
+		prefix = fmt.Sprintf("ns%d", len(p)+1)
+		p[space] = prefix
+	}
+	return prefix
+}
+
+// qualify returns the prefixed form of a QName attribute value, and the namespace declaration needed for it
+func qualify(name xml.Name, prefixes namespacePrefixes) (string, *xml.Attr) {
+	if name.Space == "" {
+		return name.Local, nil
+	}
+	if prefix, ok := encodingPrefixes[name.Space]; ok {
+		return prefix + ":" + name.Local, nil
+	}
+	prefix := prefixes.prefix(name.Space)
+	return prefix + ":" + name.Local, &xml.Attr{Name: xml.Name{Space: "", Local: "xmlns:" + prefix}, Value: name.Space}
+}
+
+func typeAttrs(typ xml.Name, prefixes namespacePrefixes) []xml.Attr {
+	if typ.Local == "" {
+		return nil
+	}
+	value, decl := qualify(typ, prefixes)
+	attrs := []xml.Attr{{Name: xml.Name{Space: "", Local: "xsi:type"}, Value: value}}
+	if decl != nil {
+		attrs = append(attrs, *decl)
+	}
+	return attrs
+}
+
+// encodeEncoded writes an accessor using the SOAP 1.1 encoding rules, every accessor is annotated with its xsi:type.
+// typ overrides the type derived from the Go value.
+// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383512
+func (tokens *tokenData) encodeEncoded(name string, v reflect.Value, typ xml.Name) error {
+	start := xml.StartElement{Name: xml.Name{Space: "", Local: name}}
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Space: "", Local: "xsi:nil"}, Value: "true"})
+			tokens.data = append(tokens.data, segment{token: start}, segment{token: start.End()})
+			return nil
+		}
+		if v.Type().Implements(marshalerType) {
+			break
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Space: "", Local: "xsi:nil"}, Value: "true"})
+		tokens.data = append(tokens.data, segment{token: start}, segment{token: start.End()})
+		return nil
+	}
+
+	if typ.Local == "" {
+		if registered, ok := tokens.types.orDefault().typeName(v.Type()); ok {
+			typ = registered
+		} else {
+			typ, _ = xsdTypeOf(v.Type())
+		}
+	}
+	if v.Type().Implements(marshalerType) || reflect.PointerTo(v.Type()).Implements(marshalerType) {
+		start.Attr = append(start.Attr, typeAttrs(typ, tokens.prefixes)...)
+		tokens.data = append(tokens.data, segment{namedValue: named{content: v.Interface(), start: start}})
+		return nil
+	}
+
+	switch {
+	case v.Type() == timeType:
+		start.Attr = typeAttrs(typ, tokens.prefixes)
+		text := v.Interface().(time.Time).Format(time.RFC3339Nano)
+		tokens.data = append(tokens.data, segment{token: start}, segment{token: xml.CharData(text)}, segment{token: start.End()})
+	case v.Type() == byteSliceType:
+		start.Attr = typeAttrs(typ, tokens.prefixes)
+		text := base64.StdEncoding.EncodeToString(v.Bytes())
+		tokens.data = append(tokens.data, segment{token: start}, segment{token: xml.CharData(text)}, segment{token: start.End()})
+	case v.Type() == arrayParamType || v.Kind() == reflect.Map || v.Kind() == reflect.Struct:
+		start.Attr = typeAttrs(typ, tokens.prefixes)
+		tokens.data = append(tokens.data, segment{token: start})
+		if err := tokens.encodeEncodedFields(v); err != nil {
+			return err
+		}
+		tokens.data = append(tokens.data, segment{token: start.End()})
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		return tokens.encodeEncodedArray(start, v)
+	default:
+		text, ok := simpleText(v)
+		if !ok {
+			return fmt.Errorf("can't encode %q: values of type %s are not supported", name, v.Type())
+		}
+		start.Attr = typeAttrs(typ, tokens.prefixes)
+		tokens.data = append(tokens.data, segment{token: start}, segment{token: xml.CharData(text)}, segment{token: start.End()})
+	}
+	return nil
+}
+
+// encodeEncodedArray writes a SOAP-ENC:Array, the item type is derived from the element type of the slice.
+// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383522
+func (tokens *tokenData) encodeEncodedArray(start xml.StartElement, v reflect.Value) error {
+	itemType, ok := xsdTypeOf(v.Type().Elem())
+	if !ok {
+		itemType = xml.Name{Space: xsdNS, Local: "anyType"}
+	}
+	arrayType, _ := qualify(itemType, tokens.prefixes)
+	start.Attr = append(typeAttrs(xml.Name{Space: soapEncodingNS, Local: "Array"}, tokens.prefixes), xml.Attr{
+		Name:  xml.Name{Space: "", Local: "SOAP-ENC:arrayType"},
+		Value: fmt.Sprintf("%s[%d]", arrayType, v.Len()),
+	})
+	tokens.data = append(tokens.data, segment{token: start})
+	for i := 0; i < v.Len(); i++ {
+		if err := tokens.encodeEncoded("item", v.Index(i), xml.Name{}); err != nil {
+			return err
+		}
+	}
+	tokens.data = append(tokens.data, segment{token: start.End()})
+	return nil
+}
+
+// encodeEncodedFields writes the entries of a map, ArrayParams or the fields of a struct as accessors.
+// Map keys are sorted to get a deterministic order.
+func (tokens *tokenData) encodeEncodedFields(v reflect.Value) error {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == arrayParamType:
+		for _, pair := range v.Interface().(ArrayParams) {
+			label, _ := pair[0].(string)
+			if err := tokens.encodeEncoded(label, reflect.ValueOf(pair[1]), xml.Name{}); err != nil {
+				return err
+			}
+		}
+	case v.Kind() == reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		for _, key := range keys {
+			if err := tokens.encodeEncoded(key.String(), v.MapIndex(key), xml.Name{}); err != nil {
+				return err
+			}
+		}
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() || field.Name == "XMLName" {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("xml"), ",")
+			if name == "-" || strings.Contains(opts, "attr") {
+				continue
+			}
+			if strings.Contains(opts, "omitempty") && v.Field(i).IsZero() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if err := tokens.encodeEncoded(name, v.Field(i), xml.Name{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func simpleText(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32), true
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), true
+	}
+	return "", false
+}
+
+// Array decodes a SOAP encoded array, every child element is decoded as an item regardless of its name.
+// When encoded, the items are written as "item" elements.
+// see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383522
+type Array[T any] []T
+
+func (a *Array[T]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			var item T
+			if err := d.DecodeElement(&item, &t); err != nil {
+				return err
+			}
+			*a = append(*a, item)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func (a Array[T]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, item := range a {
+		if err := e.EncodeElement(item, xml.StartElement{Name: xml.Name{Local: "item"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
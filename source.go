@@ -0,0 +1,521 @@
+package gosoap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// WSDLDocument is a WSDL or XSD document loaded by a WSDLSource or an ImportResolver
+type WSDLDocument struct {
+	// Location of the document. Relative imports are resolved against it and
+	// it identifies the document when detecting import cycles.
+	Location string
+	Content  []byte
+	// Resolver loads the documents imported by this document, imports are not followed if it is nil.
+	// Imported documents without a resolver inherit the resolver of the importing document.
+	Resolver ImportResolver
+
+	// compiled definitions restored from a snapshot, Content is not parsed if they are set
+	compiled *wsdlDefinitions
+}
+
+// ImportResolver loads documents by location, it is used by the WSDL sources to load the WSDL and
+// to follow wsdl:import, xsd:import and xsd:include.
+type ImportResolver interface {
+	// Resolve loads the document at location, base is the location of the importing document and empty
+	// for the WSDL itself. Relative locations are resolved against base.
+	Resolve(ctx context.Context, base, location string) (*WSDLDocument, error)
+}
+
+// ImportResolverFunc is an adapter to use a function as ImportResolver
+type ImportResolverFunc func(ctx context.Context, base, location string) (*WSDLDocument, error)
+
+func (f ImportResolverFunc) Resolve(ctx context.Context, base, location string) (*WSDLDocument, error) {
+	return f(ctx, base, location)
+}
+
+// WSDLSource returns the raw WSDL a Client is created from.
+// The sources of this package also follow the imports of the WSDL and load it with the context passed to
+// NewClientWithContext or Client.Refresh, imports of the WSDL returned by other functions are not followed.
+type WSDLSource func(config *Config) ([]byte, error)
+
+// sourceLoad connects the sources of this package with the loader through Config: it carries the context
+// of the load and receives the document the source loaded, so the imports can be resolved relative to its location
+type sourceLoad struct {
+	ctx context.Context
+	doc *WSDLDocument
+}
+
+// documentSource creates a WSDLSource from a function loading the WSDL document.
+// Called outside of a load, e.g. by a function wrapping the source, the document is loaded with context.Background.
+func documentSource(load func(ctx context.Context, config *Config) (*WSDLDocument, error)) WSDLSource {
+	return func(config *Config) ([]byte, error) {
+		if config == nil {
+			config = &Config{}
+		}
+		ctx := context.Background()
+		if config.load != nil {
+			ctx = config.load.ctx
+		}
+		doc, err := load(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		if config.load != nil {
+			config.load.doc = doc
+		}
+		return doc.Content, nil
+	}
+}
+
+// SourceFromURI loads the WSDL from a http(s) or file URI, imports are resolved relative to the URI.
+// Documents fetched over http(s) are cached in Config.WSDLCacheDir if it is set.
+func SourceFromURI(uri string) WSDLSource {
+	return documentSource(func(ctx context.Context, config *Config) (*WSDLDocument, error) {
+		return loadDocument(ctx, &uriResolver{client: config.Client, cacheDir: config.WSDLCacheDir}, uri)
+	})
+}
+
+// SourceFromBytes uses raw as the WSDL, imports are not followed.
+func SourceFromBytes(raw []byte) WSDLSource {
+	return func(config *Config) ([]byte, error) {
+		return raw, nil
+	}
+}
+
+// SourceFromBytesWithResolver uses raw as the WSDL and loads the documents it imports with resolver.
+// The base passed to the resolver for imports of the WSDL is empty.
+func SourceFromBytesWithResolver(raw []byte, resolver ImportResolver) WSDLSource {
+	return documentSource(func(ctx context.Context, config *Config) (*WSDLDocument, error) {
+		return &WSDLDocument{Content: raw, Resolver: resolver}, nil
+	})
+}
+
+// SourceFromResolver loads the WSDL at location with resolver, imports are loaded with the same resolver.
+func SourceFromResolver(resolver ImportResolver, location string) WSDLSource {
+	return documentSource(func(ctx context.Context, config *Config) (*WSDLDocument, error) {
+		return loadDocument(ctx, resolver, location)
+	})
+}
+
+// SourceFromFS loads the WSDL at path from fsys, imports are resolved relative to path.
+// It can be used to load WSDLs and the schemas they import from an embed.FS.
+func SourceFromFS(fsys fs.FS, path string) WSDLSource {
+	return SourceFromResolver(FSResolver(fsys), path)
+}
+
+// loadDocument loads the WSDL at location, its imports are loaded with the same resolver
+func loadDocument(ctx context.Context, resolver ImportResolver, location string) (*WSDLDocument, error) {
+	doc, err := resolver.Resolve(ctx, "", location)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Resolver == nil {
+		doc.Resolver = resolver
+	}
+	return doc, nil
+}
+
+// URIResolver loads documents from http(s) and file URIs, relative locations are resolved against the base URI.
+func URIResolver(c *http.Client) ImportResolver {
+	return &uriResolver{client: c}
+}
+
+type uriResolver struct {
+	client *http.Client
+	// cacheDir is the directory of the wsdlCache, documents are not cached if it is empty
+	cacheDir string
+}
+
+func (r *uriResolver) Resolve(ctx context.Context, base, location string) (*WSDLDocument, error) {
+	u, err := resolveLocation(base, location)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch WSDL resource: %w", err)
+	}
+	var content []byte
+	if r.cacheDir != "" && isHTTP(u) {
+		content, err = wsdlCache(r.cacheDir).fetch(ctx, u, r.client)
+	} else {
+		content, err = readWsdlBody(ctx, u, r.client)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch WSDL resource: %w", err)
+	}
+	return &WSDLDocument{Location: u, Content: content, Resolver: r}, nil
+}
+
+// FSResolver loads documents from fsys, locations are slash separated paths relative to the base document.
+func FSResolver(fsys fs.FS) ImportResolver {
+	return &fsResolver{fsys: fsys}
+}
+
+type fsResolver struct {
+	fsys fs.FS
+}
+
+func (r *fsResolver) Resolve(ctx context.Context, base, location string) (*WSDLDocument, error) {
+	if u, err := url.Parse(location); err != nil || u.Scheme != "" {
+		return nil, fmt.Errorf("could not read WSDL resource: location %q is not a path", location)
+	}
+	p := location
+	if !path.IsAbs(p) && base != "" {
+		p = path.Join(path.Dir(base), p)
+	}
+	p = strings.TrimPrefix(path.Clean(p), "/")
+	content, err := fs.ReadFile(r.fsys, p)
+	if err != nil {
+		return nil, fmt.Errorf("could not read WSDL resource: %w", err)
+	}
+	return &WSDLDocument{Location: p, Content: content, Resolver: r}, nil
+}
+
+// resolveLocation resolves a possibly relative location against a base URI
+func resolveLocation(base, location string) (string, error) {
+	if base == "" {
+		return location, nil
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	l, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(l).String(), nil
+}
+
+func readWsdlBody(ctx context.Context, u string, c *http.Client) ([]byte, error) {
+	res, err := getWsdlBody(ctx, u, c)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	return io.ReadAll(res)
+}
+
+func getWsdlBody(ctx context.Context, u string, c *http.Client) (reader io.ReadCloser, err error) {
+	parse, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	if parse.Scheme == "file" {
+		outFile, err := os.Open(parse.Path)
+		if err != nil {
+			return nil, err
+		}
+		return outFile, nil
+	}
+	r, err := httpGet(ctx, u, c, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStatus(r); err != nil {
+		r.Body.Close()
+		return nil, err
+	}
+	return r.Body, nil
+}
+
+func httpGet(ctx context.Context, u string, c *http.Client, header http.Header) (*http.Response, error) {
+	if c == nil {
+		c = &http.Client{}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return c.Do(req)
+}
+
+// checkStatus makes sure a response is successful, error pages would otherwise be parsed as WSDL
+func checkStatus(r *http.Response) error {
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return fmt.Errorf("unexpected HTTP status %q", r.Status)
+	}
+	return nil
+}
+
+func isHTTP(u string) bool {
+	return strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")
+}
+
+// getWSDLDefinitions loads the WSDL and everything it imports into one definitions model
+func getWSDLDefinitions(ctx context.Context, source WSDLSource, c *Config) (*wsdlDefinitions, error) {
+	config := *c
+	config.load = &sourceLoad{ctx: ctx}
+	raw, err := source(&config)
+	if err != nil {
+		return nil, fmt.Errorf("could not load WSDL: %w", err)
+	}
+	doc := &WSDLDocument{Content: raw}
+	if loaded := config.load.doc; loaded != nil {
+		if loaded.compiled != nil {
+			return loaded.compiled, nil
+		}
+		// a function wrapping a source of this package can change the content
+		doc = loaded
+		doc.Content = raw
+	}
+	l := &wsdlLoader{ctx: ctx, loaded: map[string]bool{}}
+	d, err := l.loadDefinitions(doc, nil)
+	if err != nil {
+		return nil, err
+	}
+	d.compile()
+	return d, nil
+}
+
+// wsdlLoader follows wsdl:import, xsd:import and xsd:include, every document is loaded only once
+type wsdlLoader struct {
+	// ctx of the load, the loader only lives while the definitions are loaded
+	ctx    context.Context
+	loaded map[string]bool
+}
+
+func decodeDocument(content []byte, v any) error {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.CharsetReader = charset.NewReaderLabel
+	return decoder.Decode(v)
+}
+
+// rootName returns the name of the root element of a document
+func rootName(content []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.CharsetReader = charset.NewReaderLabel
+	for {
+		t, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if start, ok := t.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// resolve loads an imported document, it returns nil if the document was already loaded
+func (l *wsdlLoader) resolve(parent *WSDLDocument, resolver ImportResolver, location string) (*WSDLDocument, error) {
+	doc, err := resolver.Resolve(l.ctx, parent.Location, location)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve import %q: %w", location, err)
+	}
+	key := doc.Location
+	if key == "" {
+		key = location
+	}
+	if l.loaded[key] {
+		return nil, nil
+	}
+	l.loaded[key] = true
+	if doc.Resolver == nil {
+		doc.Resolver = resolver
+	}
+	return doc, nil
+}
+
+func (l *wsdlLoader) loadDefinitions(doc *WSDLDocument, resolver ImportResolver) (*wsdlDefinitions, error) {
+	if doc.Location != "" {
+		l.loaded[doc.Location] = true
+	}
+	if doc.Resolver != nil {
+		resolver = doc.Resolver
+	}
+
+	root, err := rootName(doc.Content)
+	if err != nil {
+		return nil, err
+	}
+	var d *wsdlDefinitions
+	if root.Space == wsdl2NS {
+		if d, err = decodeDescription(doc.Content); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := decodeDocument(doc.Content, &d); err != nil {
+			return nil, err
+		}
+		d.resolveNames()
+	}
+
+	var schemas []*xsdSchema
+	for _, t := range d.Types {
+		for _, schema := range t.XsdSchema {
+			// schemas embedded in a WSDL inherit the namespace declarations of the definitions element
+			schema.Namespaces = append(append(namespaces{}, d.Attrs...), schema.Attrs...)
+			imported, err := l.loadSchemaImports(doc, resolver, schema)
+			if err != nil {
+				return nil, err
+			}
+			schemas = append(schemas, imported...)
+		}
+	}
+
+	if resolver != nil {
+		for _, imp := range d.Imports {
+			if imp.Location == "" {
+				continue
+			}
+			imported, err := l.resolve(doc, resolver, imp.Location)
+			if err != nil {
+				return nil, err
+			}
+			if imported == nil {
+				continue
+			}
+			root, err := rootName(imported.Content)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse import %q: %w", imp.Location, err)
+			}
+			if root.Space == xsdNS {
+				// wsdl:import is sometimes used for plain schemas
+				s, err := l.loadSchema(imported, "")
+				if err != nil {
+					return nil, err
+				}
+				schemas = append(schemas, s...)
+				continue
+			}
+			importedDefinitions, err := l.loadDefinitions(imported, resolver)
+			if err != nil {
+				return nil, err
+			}
+			d.merge(importedDefinitions)
+		}
+	}
+
+	if len(schemas) > 0 {
+		d.Types = append(d.Types, &wsdlTypes{XsdSchema: schemas})
+	}
+	return d, nil
+}
+
+// loadSchema loads a standalone schema document and the schemas it imports.
+// A schema without a target namespace that is included takes the namespace of the including schema.
+func (l *wsdlLoader) loadSchema(doc *WSDLDocument, includingNamespace string) ([]*xsdSchema, error) {
+	var schema *xsdSchema
+	if err := decodeDocument(doc.Content, &schema); err != nil {
+		return nil, fmt.Errorf("could not parse schema %q: %w", doc.Location, err)
+	}
+	schema.Namespaces = schema.Attrs
+	if schema.TargetNamespace == "" && includingNamespace != "" {
+		schema.TargetNamespace = includingNamespace
+		schema.Chameleon = true
+	}
+	imported, err := l.loadSchemaImports(doc, doc.Resolver, schema)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*xsdSchema{schema}, imported...), nil
+}
+
+// loadSchemaImports loads the schemas referenced by the xsd:import and xsd:include elements of a schema
+func (l *wsdlLoader) loadSchemaImports(doc *WSDLDocument, resolver ImportResolver, schema *xsdSchema) ([]*xsdSchema, error) {
+	if resolver == nil {
+		return nil, nil
+	}
+	var schemas []*xsdSchema
+	load := func(location, includingNamespace string) error {
+		if location == "" {
+			return nil
+		}
+		imported, err := l.resolve(doc, resolver, location)
+		if err != nil || imported == nil {
+			return err
+		}
+		s, err := l.loadSchema(imported, includingNamespace)
+		if err != nil {
+			return err
+		}
+		schemas = append(schemas, s...)
+		return nil
+	}
+	for _, imp := range schema.Imports {
+		if err := load(imp.SchemaLocation, ""); err != nil {
+			return nil, err
+		}
+	}
+	for _, inc := range schema.Includes {
+		if err := load(inc.SchemaLocation, schema.TargetNamespace); err != nil {
+			return nil, err
+		}
+	}
+	return schemas, nil
+}
+
+// merge adds the components of imported definitions
+func (d *wsdlDefinitions) merge(imported *wsdlDefinitions) {
+	d.Types = append(d.Types, imported.Types...)
+	d.Messages = append(d.Messages, imported.Messages...)
+	d.PortTypes = append(d.PortTypes, imported.PortTypes...)
+	d.Bindings = append(d.Bindings, imported.Bindings...)
+	d.Services = append(d.Services, imported.Services...)
+}
+
+// resolveNames resolves the names of the components and the QNames they reference,
+// this has to happen before definitions are merged because prefixes and the target namespace are local to a document.
+func (d *wsdlDefinitions) resolveNames() {
+	qname := func(local string) xml.Name {
+		return xml.Name{Space: d.TargetNamespace, Local: local}
+	}
+	for _, m := range d.Messages {
+		m.QName = qname(m.Name)
+		for _, p := range m.Parts {
+			if p.Element != "" {
+				p.ElementName = d.resolveQName(p.Element)
+			}
+			if p.Type != "" {
+				p.TypeName = d.resolveQName(p.Type)
+			}
+		}
+	}
+	for _, pt := range d.PortTypes {
+		pt.QName = qname(pt.Name)
+		for _, o := range pt.Operations {
+			for _, in := range o.Inputs {
+				in.MessageName = d.resolveQName(in.Message)
+			}
+			for _, out := range o.Outputs {
+				out.MessageName = d.resolveQName(out.Message)
+			}
+			for _, f := range o.Faults {
+				f.MessageName = d.resolveQName(f.Message)
+			}
+		}
+	}
+	for _, b := range d.Bindings {
+		b.QName = qname(b.Name)
+		b.TypeName = d.resolveQName(b.Type)
+		for _, o := range b.Operations {
+			for _, in := range o.Inputs {
+				for _, h := range in.SoapHeaders {
+					h.MessageName = d.resolveQName(h.Message)
+				}
+			}
+			for _, out := range o.Outputs {
+				for _, h := range out.SoapHeaders {
+					h.MessageName = d.resolveQName(h.Message)
+				}
+			}
+		}
+	}
+	for _, s := range d.Services {
+		for _, p := range s.Ports {
+			p.BindingName = d.resolveQName(p.Binding)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package gosoap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func schemaNamespaces(d *wsdlDefinitions) []string {
+	var namespaces []string
+	for _, t := range d.Types {
+		for _, s := range t.XsdSchema {
+			namespaces = append(namespaces, s.TargetNamespace)
+		}
+	}
+	return namespaces
+}
+
+func TestImports(t *testing.T) {
+	t.Parallel()
+	dir, err := filepath.Abs("testdata/split")
+	require.NoError(t, err)
+
+	resolver := ImportResolverFunc(func(ctx context.Context, base, location string) (*WSDLDocument, error) {
+		p := path.Join(path.Dir(base), location)
+		content, err := os.ReadFile(filepath.Join(dir, p))
+		if err != nil {
+			return nil, err
+		}
+		return &WSDLDocument{Location: p, Content: content}, nil
+	})
+	main, err := os.ReadFile(filepath.Join(dir, "main.wsdl"))
+	require.NoError(t, err)
+
+	sources := map[string]WSDLSource{
+		"file URI":       SourceFromURI("file://" + filepath.Join(dir, "main.wsdl")),
+		"bytes resolver": SourceFromBytesWithResolver(main, resolver),
+		"fs":             SourceFromFS(os.DirFS(dir), "main.wsdl"),
+	}
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			d, err := getWSDLDefinitions(context.Background(), source, &Config{})
+			require.NoError(t, err)
+			assert.Len(t, d.PortTypes, 1)
+			assert.Len(t, d.Messages, 2)
+			assert.Equal(t, []string{
+				"http://example.com/split/imports",
+				"http://example.com/split",
+				"http://example.com/split/common",
+				"http://example.com/split",
+			}, schemaNamespaces(d))
+		})
+	}
+
+	server, reqBody := newCaptureServer(t)
+	client, err := NewClient(sources["file URI"], &Config{Client: server.Client()})
+	require.NoError(t, err)
+	setAddress(client, server.URL)
+	_, err = client.Call(context.Background(), "GetQuote", Params{"Symbol": "ACME"})
+	require.NoError(t, err)
+	assert.Contains(t, string(*reqBody), `<GetQuote xmlns="http://example.com/split">`)
+}
+
+func TestSourceFromURIImports(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/split")))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(SourceFromURI(server.URL+"/main.wsdl"), &Config{Client: server.Client()})
+	require.NoError(t, err)
+	d := client.state().definitions
+	assert.Len(t, d.PortTypes, 1)
+	assert.Len(t, d.Messages, 2)
+	assert.Len(t, schemaNamespaces(d), 4)
+
+	// imports are followed if the source is wrapped
+	wrapped := func(config *Config) ([]byte, error) {
+		return SourceFromURI(server.URL + "/main.wsdl")(config)
+	}
+	client, err = NewClient(wrapped, &Config{Client: server.Client()})
+	require.NoError(t, err)
+	assert.Len(t, client.state().definitions.Messages, 2)
+}
+
+func TestSourceContext(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := NewClientWithContext(ctx, SourceFromURI(server.URL+"/service.wsdl"), &Config{Client: server.Client()})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFSResolver(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"wsdl/service.wsdl": {Data: []byte("<definitions/>")},
+		"xsd/types.xsd":     {Data: []byte("<schema/>")},
+	}
+	resolver := FSResolver(fsys)
+
+	doc, err := resolver.Resolve(context.Background(), "wsdl/service.wsdl", "../xsd/types.xsd")
+	require.NoError(t, err)
+	assert.Equal(t, "xsd/types.xsd", doc.Location)
+	assert.Equal(t, "<schema/>", string(doc.Content))
+	assert.Same(t, resolver, doc.Resolver)
+
+	doc, err = resolver.Resolve(context.Background(), "wsdl/service.wsdl", "/xsd/types.xsd")
+	require.NoError(t, err)
+	assert.Equal(t, "xsd/types.xsd", doc.Location)
+
+	_, err = resolver.Resolve(context.Background(), "wsdl/service.wsdl", "http://example.com/types.xsd")
+	assert.EqualError(t, err, `could not read WSDL resource: location "http://example.com/types.xsd" is not a path`)
+	_, err = resolver.Resolve(context.Background(), "wsdl/service.wsdl", "types.xsd")
+	assert.EqualError(t, err, "could not read WSDL resource: open wsdl/types.xsd: file does not exist")
+}
+
+func TestWSDLSource(t *testing.T) {
+	t.Parallel()
+	spec, err := os.ReadFile("./testdata/quote.wsdl")
+	require.NoError(t, err)
+	var configured *Config
+	client, err := NewClient(func(config *Config) ([]byte, error) {
+		configured = config
+		return spec, nil
+	}, &Config{Service: "QuoteService"})
+	require.NoError(t, err)
+	assert.Equal(t, "QuoteService", configured.Service)
+	assert.NotNil(t, client.Definitions().Service("QuoteService"))
+
+	_, err = NewClient(func(config *Config) ([]byte, error) {
+		return nil, errors.New("offline")
+	}, nil)
+	assert.EqualError(t, err, "could not load WSDL: offline")
+}
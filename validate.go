@@ -0,0 +1,990 @@
+package gosoap
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError lists the ways a message doesn't conform to the schema of the WSDL
+type ValidationError struct {
+	// Violations in document order
+	Violations []Violation
+}
+
+// Violation is a single schema constraint a message violates
+type Violation struct {
+	// Path of the element or attribute, like /GetQuote/Items/Item[2]/@currency
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Path+": "+v.Message)
+	}
+	return "message does not match the schema: " + strings.Join(messages, "; ")
+}
+
+// validateRequest validates the body of a request envelope against the input message of an operation.
+// Requests are accepted if the message can't be resolved or uses the SOAP encoding.
+func (s *wsdlState) validateRequest(envelope []byte, op *wsdlOperation) error {
+	if s.definitions == nil {
+		return nil
+	}
+	msg, err := s.definitions.inputMessage(s.binding, op)
+	if err != nil {
+		return nil
+	}
+	body := inputBody(op)
+	if body != nil && body.Use == "encoded" {
+		return nil
+	}
+	roots, err := parseXMLNodes(envelope)
+	if err != nil {
+		return err
+	}
+	for _, env := range elementsOf(roots) {
+		for _, b := range env.elements() {
+			if b.Name.Local != "Body" {
+				continue
+			}
+			ns := append(append(namespaces{}, env.Attr...), b.Attr...)
+			if verr := s.definitions.validateMessage(b.Children, ns, msg, body, s.binding.style(op) == "rpc"); verr != nil {
+				return verr
+			}
+		}
+	}
+	return nil
+}
+
+// validateResponse validates the body of a response against the output message of an operation.
+// Faults, SOAP encoded responses and responses whose message can't be resolved aren't validated.
+func (s *wsdlState) validateResponse(res *Response, op *wsdlOperation) *ValidationError {
+	if s.definitions == nil {
+		return nil
+	}
+	msg, err := s.definitions.outputMessage(s.binding, op)
+	if err != nil {
+		return nil
+	}
+	body := outputBody(op)
+	if body != nil && body.Use == "encoded" {
+		return nil
+	}
+	nodes, err := parseXMLFragment(res.Body, res.bodyNamespaces)
+	if err != nil {
+		return &ValidationError{Violations: []Violation{{Path: "/", Message: err.Error()}}}
+	}
+	for _, n := range elementsOf(nodes) {
+		if n.Name.Local == "Fault" && (n.Name.Space == soap11EnvelopeNS || n.Name.Space == soap12EnvelopeNS) {
+			return nil
+		}
+	}
+	return s.definitions.validateMessage(nodes, res.bodyNamespaces, msg, body, s.binding.style(op) == "rpc")
+}
+
+// validateMessage validates the content of a SOAP body against the parts of a message.
+// Document style bodies contain the elements of the parts, rpc style bodies a wrapper element with an accessor per part.
+// Bodies of document style messages with type parts can't be validated.
+func (d *wsdlDefinitions) validateMessage(nodes []*xmlNode, ns namespaces, msg *wsdlMessage, body *soapBody, rpc bool) *ValidationError {
+	v := &validator{set: d.schemas, patterns: map[string]compiledPattern{}}
+	group := &xsdModelGroup{Kind: "sequence"}
+	for _, part := range msg.Parts {
+		if !body.includes(part.Name) {
+			continue
+		}
+		switch {
+		case part.Element != "":
+			group.Particles = append(group.Particles, &xsdParticle{Element: &xsdElement{RefName: part.ElementName, QName: part.ElementName}})
+		case rpc:
+			group.Particles = append(group.Particles, &xsdParticle{Element: &xsdElement{QName: xml.Name{Local: part.Name}, TypeName: part.TypeName}})
+		default:
+			return nil
+		}
+	}
+	c := scope{ns: ns}
+	if rpc {
+		elements := elementsOf(nodes)
+		if len(elements) != 1 {
+			v.report("/", "expected a single wrapper element")
+			return v.result()
+		}
+		wrapper := elements[0]
+		c = c.child(wrapper, "/"+wrapper.Name.Local)
+		nodes = wrapper.Children
+	}
+	v.children(nodes, []*xsdModelGroup{group}, false, c)
+	return v.result()
+}
+
+// elementsOf returns the elements of a node list, skipping character data
+func elementsOf(nodes []*xmlNode) []*xmlNode {
+	parent := xmlNode{Children: nodes}
+	return parent.elements()
+}
+
+// validator checks XML trees against the components of a schema set
+type validator struct {
+	set        *xsdSchemaSet
+	violations []Violation
+	// patterns caches compiled pattern facets
+	patterns map[string]compiledPattern
+}
+
+// scope is the position of the validator in the document
+type scope struct {
+	path string
+	// ns are the namespace declarations in scope, used to resolve xsi:type values
+	ns namespaces
+}
+
+func (c scope) child(n *xmlNode, path string) scope {
+	return scope{path: path, ns: append(append(namespaces{}, c.ns...), n.Attr...)}
+}
+
+func (v *validator) report(path, format string, args ...any) {
+	if path == "" {
+		path = "/"
+	}
+	v.violations = append(v.violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) result() *ValidationError {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v.violations}
+}
+
+// children validates the child nodes of an element against a content model
+func (v *validator) children(nodes []*xmlNode, groups []*xsdModelGroup, mixed bool, c scope) {
+	var elements []*xmlNode
+	for _, n := range nodes {
+		if !n.isText() {
+			elements = append(elements, n)
+		} else if !mixed && strings.TrimSpace(n.Text) != "" {
+			v.report(c.path, "unexpected text %q", strings.TrimSpace(n.Text))
+		}
+	}
+	m := &matcher{validator: v, elements: elements, scope: c}
+	for _, g := range groups {
+		m.group(g, occurs(g.MinOccurs), occurs(g.MaxOccurs))
+	}
+	for _, n := range elements[m.next:] {
+		v.report(m.path(n), "unexpected element %q", n.Name.Local)
+	}
+}
+
+// matcher matches a list of sibling elements against model groups, it consumes elements greedily
+// which is unambiguous for schemas that satisfy the unique particle attribution constraint.
+type matcher struct {
+	*validator
+	elements []*xmlNode
+	next     int
+	scope    scope
+}
+
+// path returns the path of a child element, the position is added if the name is repeated
+func (m *matcher) path(n *xmlNode) string {
+	position, count := 0, 0
+	for _, e := range m.elements {
+		if e.Name == n.Name {
+			count++
+			if e == n {
+				position = count
+			}
+		}
+	}
+	p := m.scope.path + "/" + n.Name.Local
+	if count > 1 {
+		p += "[" + strconv.Itoa(position) + "]"
+	}
+	return p
+}
+
+func (m *matcher) current() (xml.Name, bool) {
+	if m.next >= len(m.elements) {
+		return xml.Name{}, false
+	}
+	return m.elements[m.next].Name, true
+}
+
+func (m *matcher) group(g *xsdModelGroup, min, max int) {
+	if g == nil {
+		return
+	}
+	for count := 0; max < 0 || count < max; count++ {
+		start := m.next
+		if count >= min {
+			name, ok := m.current()
+			if !ok || !m.groupStarts(g, name, 0) {
+				return
+			}
+		}
+		m.groupOnce(g)
+		if m.next == start && count >= min {
+			return
+		}
+	}
+}
+
+func (m *matcher) groupOnce(g *xsdModelGroup) {
+	switch g.Kind {
+	case "choice":
+		if name, ok := m.current(); ok {
+			for _, p := range g.Particles {
+				if m.starts(p, name, 0) {
+					m.particle(p)
+					return
+				}
+			}
+		}
+		for _, p := range g.Particles {
+			if m.emptiable(p, 0) {
+				return
+			}
+		}
+		m.report(m.scope.path, "missing one of the elements %s", strings.Join(m.names(g.Particles), ", "))
+	case "all":
+		remaining := append([]*xsdParticle{}, g.Particles...)
+	next:
+		for name, ok := m.current(); ok; name, ok = m.current() {
+			for i, p := range remaining {
+				if m.starts(p, name, 0) {
+					m.particle(p)
+					remaining = append(remaining[:i], remaining[i+1:]...)
+					continue next
+				}
+			}
+			break
+		}
+		for _, p := range remaining {
+			if !m.emptiable(p, 0) {
+				m.particle(p)
+			}
+		}
+	default:
+		for _, p := range g.Particles {
+			m.particle(p)
+		}
+	}
+}
+
+func (m *matcher) particle(p *xsdParticle) {
+	switch {
+	case p.Element != nil:
+		min, max := occurs(p.Element.MinOccurs), occurs(p.Element.MaxOccurs)
+		count := 0
+		for ; max < 0 || count < max; count++ {
+			name, ok := m.current()
+			if !ok {
+				break
+			}
+			decl := m.match(p.Element, name)
+			if decl == nil {
+				break
+			}
+			n := m.elements[m.next]
+			m.element(n, decl, m.scope.child(n, m.path(n)))
+			m.next++
+		}
+		if count < min {
+			m.report(m.scope.path, "missing element %q", m.set.resolveElement(p.Element).QName.Local)
+		}
+	case p.Any != nil:
+		min, max := occurs(p.Any.MinOccurs), occurs(p.Any.MaxOccurs)
+		count := 0
+		for ; max < 0 || count < max; count++ {
+			name, ok := m.current()
+			if !ok || !wildcardAllows(p.Any, name) {
+				break
+			}
+			n := m.elements[m.next]
+			if decl := m.set.element(name); decl != nil && p.Any.ProcessContents != "skip" {
+				m.element(n, decl, m.scope.child(n, m.path(n)))
+			} else if p.Any.ProcessContents == "" || p.Any.ProcessContents == "strict" {
+				m.report(m.path(n), "element %q is not declared", name.Local)
+			}
+			m.next++
+		}
+		if count < min {
+			m.report(m.scope.path, "missing element matching the wildcard %q", p.Any.Namespace)
+		}
+	case p.ModelGroup != nil:
+		m.group(p.ModelGroup, occurs(p.ModelGroup.MinOccurs), occurs(p.ModelGroup.MaxOccurs))
+	case p.Group != nil:
+		m.group(m.set.group(p.Group).modelGroup(), occurs(p.Group.MinOccurs), occurs(p.Group.MaxOccurs))
+	}
+}
+
+// match returns the declaration of the element or one of its substitutes with the given name
+func (v *validator) match(e *xsdElement, name xml.Name) *xsdElement {
+	for _, s := range v.set.substitutes(e) {
+		if s.QName == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// starts reports whether a particle can start with an element of the given name
+func (v *validator) starts(p *xsdParticle, name xml.Name, depth int) bool {
+	if depth > 32 {
+		return false
+	}
+	switch {
+	case p.Element != nil:
+		return occurs(p.Element.MaxOccurs) != 0 && v.match(p.Element, name) != nil
+	case p.Any != nil:
+		return wildcardAllows(p.Any, name)
+	case p.ModelGroup != nil:
+		return v.groupStarts(p.ModelGroup, name, depth+1)
+	case p.Group != nil:
+		return v.groupStarts(v.set.group(p.Group).modelGroup(), name, depth+1)
+	}
+	return false
+}
+
+func (v *validator) groupStarts(g *xsdModelGroup, name xml.Name, depth int) bool {
+	if g == nil {
+		return false
+	}
+	for _, p := range g.Particles {
+		if v.starts(p, name, depth) {
+			return true
+		}
+		if g.Kind == "sequence" && !v.emptiable(p, depth) {
+			return false
+		}
+	}
+	return false
+}
+
+// emptiable reports whether a particle can match no elements at all
+func (v *validator) emptiable(p *xsdParticle, depth int) bool {
+	if depth > 32 {
+		return true
+	}
+	var g *xsdModelGroup
+	switch {
+	case p.Element != nil:
+		return occurs(p.Element.MinOccurs) == 0
+	case p.Any != nil:
+		return occurs(p.Any.MinOccurs) == 0
+	case p.ModelGroup != nil:
+		if occurs(p.ModelGroup.MinOccurs) == 0 {
+			return true
+		}
+		g = p.ModelGroup
+	case p.Group != nil:
+		if occurs(p.Group.MinOccurs) == 0 {
+			return true
+		}
+		g = v.set.group(p.Group).modelGroup()
+	}
+	if g == nil {
+		return true
+	}
+	for _, child := range g.Particles {
+		empty := v.emptiable(child, depth+1)
+		if g.Kind == "choice" && empty {
+			return true
+		}
+		if g.Kind != "choice" && !empty {
+			return false
+		}
+	}
+	return g.Kind != "choice" || len(g.Particles) == 0
+}
+
+func (v *validator) names(particles []*xsdParticle) []string {
+	var names []string
+	for _, p := range particles {
+		switch {
+		case p.Element != nil:
+			names = append(names, strconv.Quote(v.set.resolveElement(p.Element).QName.Local))
+		case p.ModelGroup != nil:
+			names = append(names, v.names(p.ModelGroup.Particles)...)
+		case p.Group != nil:
+			if g := v.set.group(p.Group).modelGroup(); g != nil {
+				names = append(names, v.names(g.Particles)...)
+			}
+		}
+	}
+	return names
+}
+
+// wildcardAllows checks the namespace constraint of a wildcard.
+// ##other is approximated as any qualified name because the target namespace of the wildcard isn't tracked.
+func wildcardAllows(a *xsdAny, name xml.Name) bool {
+	constraint := strings.Fields(a.Namespace)
+	if len(constraint) == 0 {
+		return true
+	}
+	for _, c := range constraint {
+		switch c {
+		case "##any":
+			return true
+		case "##other":
+			if name.Space != "" {
+				return true
+			}
+		case "##local":
+			if name.Space == "" {
+				return true
+			}
+		default:
+			if c == name.Space || c == "##targetNamespace" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// element validates an element against its declaration
+func (v *validator) element(n *xmlNode, decl *xsdElement, c scope) {
+	decl = v.set.resolveElement(decl)
+	if nilled, _ := n.attr(xsiNS, "nil"); nilled == "true" || nilled == "1" {
+		if !decl.Nillable {
+			v.report(c.path, "element is not nillable")
+		}
+		if len(n.elements()) > 0 || strings.TrimSpace(n.text()) != "" {
+			v.report(c.path, "nil element must be empty")
+		}
+		return
+	}
+	ct, st, builtin := v.set.elementType(decl)
+	if typ, ok := n.attr(xsiNS, "type"); ok {
+		if name := c.ns.resolve(typ); name.Local != "" {
+			ct, st, builtin = v.set.namedType(name)
+			if ct == nil && st == nil && !isBuiltinType(builtin) {
+				v.report(c.path, "unknown type %q", typ)
+				return
+			}
+		}
+	}
+	if ct == nil {
+		v.simpleElement(n, st, builtin, c)
+		if decl.Fixed != "" && n.text() != decl.Fixed {
+			v.report(c.path, "value must be %q", decl.Fixed)
+		}
+		return
+	}
+	v.attributes(n, ct, c)
+	if ct.SimpleContent != nil {
+		st, builtin, facets := v.simpleContent(ct)
+		v.simpleElement(n, st, builtin, c)
+		for _, f := range facets {
+			v.facets(n.text(), f, builtin, c.path)
+		}
+		return
+	}
+	mixed := ct.Mixed || (ct.ComplexContent != nil && ct.ComplexContent.Mixed)
+	v.children(n.Children, v.set.contentModel(ct), mixed, c)
+}
+
+// simpleElement validates an element with a simple type
+func (v *validator) simpleElement(n *xmlNode, st *xsdSimpleType, builtin xml.Name, c scope) {
+	if builtin.Local == "anyType" {
+		return
+	}
+	if len(n.elements()) > 0 {
+		v.report(c.path, "element must not contain elements")
+		return
+	}
+	v.value(n.text(), st, builtin, c.path)
+}
+
+// simpleContent returns the simple type of a complex type with simple content and the facets its restrictions add
+func (v *validator) simpleContent(ct *xsdComplexType) (*xsdSimpleType, xml.Name, []xsdFacets) {
+	var facets []xsdFacets
+	for _, t := range v.set.baseTypes(ct) {
+		d, extension := t.derivation()
+		if d == nil {
+			break
+		}
+		if !extension && len(d.Facets) > 0 {
+			facets = append(facets, d.Facets)
+		}
+		if _, ok := v.set.complexTypes[d.BaseName]; !ok {
+			_, st, builtin := v.set.namedType(d.BaseName)
+			return st, builtin, facets
+		}
+	}
+	return nil, xml.Name{Space: xsdNS, Local: "anySimpleType"}, facets
+}
+
+// attributes validates the attributes of an element against the attribute uses of its type
+func (v *validator) attributes(n *xmlNode, ct *xsdComplexType, c scope) {
+	uses := v.set.attributeUses(ct)
+	declared := map[xml.Name]*xsdAttribute{}
+	for _, use := range uses {
+		a := v.set.attribute(use)
+		declared[a.QName] = a
+		value, present := n.attr(a.QName.Space, a.QName.Local)
+		path := c.path + "/@" + a.QName.Local
+		switch {
+		case use.Use == "prohibited" && present:
+			v.report(path, "attribute is prohibited")
+		case use.Use == "required" && !present:
+			v.report(c.path, "missing attribute %q", a.QName.Local)
+		case present:
+			fixed := use.Fixed
+			if fixed == "" {
+				fixed = a.Fixed
+			}
+			if fixed != "" && value != fixed {
+				v.report(path, "value must be %q", fixed)
+			}
+			st, builtin := a.SimpleType, xml.Name{}
+			if st == nil {
+				_, st, builtin = v.set.namedType(a.TypeName)
+				if a.TypeName.Local == "" {
+					builtin = xml.Name{Space: xsdNS, Local: "anySimpleType"}
+				}
+			}
+			v.value(value, st, builtin, path)
+		}
+	}
+	if v.anyAttribute(ct) {
+		return
+	}
+	for _, a := range n.Attr {
+		switch {
+		case a.Name.Space == "xmlns", a.Name.Space == "" && a.Name.Local == "xmlns", a.Name.Space == xsiNS, a.Name.Space == xmlNS:
+		case declared[a.Name] == nil:
+			v.report(c.path+"/@"+a.Name.Local, "unexpected attribute")
+		}
+	}
+}
+
+func (v *validator) anyAttribute(ct *xsdComplexType) bool {
+	for _, t := range v.set.baseTypes(ct) {
+		if t.AnyAttribute != nil {
+			return true
+		}
+		if d, _ := t.derivation(); d != nil && d.AnyAttribute != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// value validates a lexical value against a simple type, exactly one of st and builtin is set
+func (v *validator) value(value string, st *xsdSimpleType, builtin xml.Name, path string) {
+	if msg := v.checkValue(value, st, builtin, 0); msg != "" {
+		v.report(path, "%s", msg)
+	}
+}
+
+// checkValue returns a description of the first problem of a value or an empty string if it is valid
+func (v *validator) checkValue(value string, st *xsdSimpleType, builtin xml.Name, depth int) string {
+	if depth > 32 {
+		return ""
+	}
+	if st == nil {
+		if !isBuiltinType(builtin) {
+			return ""
+		}
+		return checkBuiltin(value, builtin.Local)
+	}
+	switch {
+	case st.Restriction != nil:
+		r := st.Restriction
+		base, baseBuiltin := r.SimpleType, xml.Name{}
+		if base == nil {
+			_, base, baseBuiltin = v.set.namedType(r.BaseName)
+		}
+		if msg := v.checkValue(value, base, baseBuiltin, depth+1); msg != "" {
+			return msg
+		}
+		return v.checkFacets(value, r.Facets, v.primitive(st, 0))
+	case st.List != nil:
+		item, itemBuiltin := st.List.SimpleType, xml.Name{}
+		if item == nil {
+			_, item, itemBuiltin = v.set.namedType(st.List.ItemTypeName)
+		}
+		for _, field := range strings.Fields(value) {
+			if msg := v.checkValue(field, item, itemBuiltin, depth+1); msg != "" {
+				return fmt.Sprintf("list item %q: %s", field, msg)
+			}
+		}
+	case st.Union != nil:
+		for _, member := range st.Union.MemberTypeNames {
+			_, memberType, memberBuiltin := v.set.namedType(member)
+			if v.checkValue(value, memberType, memberBuiltin, depth+1) == "" {
+				return ""
+			}
+		}
+		for _, member := range st.Union.SimpleTypes {
+			if v.checkValue(value, member, xml.Name{}, depth+1) == "" {
+				return ""
+			}
+		}
+		return fmt.Sprintf("value %q does not match any member type of the union", value)
+	}
+	return ""
+}
+
+// primitive returns the built-in type a simple type is derived from, "list" for list types and "union" for unions
+func (v *validator) primitive(st *xsdSimpleType, depth int) string {
+	if st == nil || depth > 32 {
+		return ""
+	}
+	switch {
+	case st.List != nil:
+		return "list"
+	case st.Union != nil:
+		return "union"
+	case st.Restriction != nil:
+		if st.Restriction.SimpleType != nil {
+			return v.primitive(st.Restriction.SimpleType, depth+1)
+		}
+		_, base, builtin := v.set.namedType(st.Restriction.BaseName)
+		if base == nil {
+			return builtin.Local
+		}
+		return v.primitive(base, depth+1)
+	}
+	return ""
+}
+
+// facets validates a value against the facets of a simple content restriction
+func (v *validator) facets(value string, facets xsdFacets, builtin xml.Name, path string) {
+	if msg := v.checkFacets(value, facets, builtin.Local); msg != "" {
+		v.report(path, "%s", msg)
+	}
+}
+
+// checkFacets checks the constraining facets of one restriction step, primitive is the built-in base type
+func (v *validator) checkFacets(value string, facets xsdFacets, primitive string) string {
+	normalized := value
+	if !isStringType(primitive) {
+		normalized = collapseWhitespace(value)
+	}
+	if enumeration := facets.values("enumeration"); len(enumeration) > 0 && !containsString(enumeration, normalized) {
+		return fmt.Sprintf("value %q is not one of %s", normalized, quoteAll(enumeration))
+	}
+	if patterns := facets.values("pattern"); len(patterns) > 0 {
+		matched := false
+		for _, p := range patterns {
+			re, err := v.pattern(p)
+			if err != nil {
+				return fmt.Sprintf("the pattern %q can't be checked: %v", p, err)
+			}
+			if re.MatchString(normalized) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Sprintf("value %q does not match the pattern %s", normalized, quoteAll(patterns))
+		}
+	}
+	length := valueLength(normalized, primitive)
+	for _, kind := range []string{"length", "minLength", "maxLength"} {
+		limit, ok := facets.get(kind)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil {
+			continue
+		}
+		if (kind == "length" && length != n) || (kind == "minLength" && length < n) || (kind == "maxLength" && length > n) {
+			return fmt.Sprintf("length %d violates %s %d", length, kind, n)
+		}
+	}
+	bounds := []struct {
+		kind string
+		ok   func(c int) bool
+		text string
+	}{
+		{"minInclusive", func(c int) bool { return c >= 0 }, "less than"},
+		{"maxInclusive", func(c int) bool { return c <= 0 }, "greater than"},
+		{"minExclusive", func(c int) bool { return c > 0 }, "less than or equal to"},
+		{"maxExclusive", func(c int) bool { return c < 0 }, "greater than or equal to"},
+	}
+	for _, b := range bounds {
+		limit, ok := facets.get(b.kind)
+		if !ok {
+			continue
+		}
+		if c, comparable := compareValues(normalized, strings.TrimSpace(limit), primitive); comparable && !b.ok(c) {
+			return fmt.Sprintf("value %s is %s %s", normalized, b.text, strings.TrimSpace(limit))
+		}
+	}
+	total, fraction := digits(normalized)
+	if limit, ok := facets.get("totalDigits"); ok {
+		if n, err := strconv.Atoi(strings.TrimSpace(limit)); err == nil && total > n {
+			return fmt.Sprintf("value %s has more than %d digits", normalized, n)
+		}
+	}
+	if limit, ok := facets.get("fractionDigits"); ok {
+		if n, err := strconv.Atoi(strings.TrimSpace(limit)); err == nil && fraction > n {
+			return fmt.Sprintf("value %s has more than %d fraction digits", normalized, n)
+		}
+	}
+	return ""
+}
+
+// pattern compiles an XSD regular expression, the error of a pattern Go can't compile is cached as well.
+// XSD patterns are implicitly anchored and use \i and \c for XML name characters.
+func (v *validator) pattern(p string) (*regexp.Regexp, error) {
+	if c, ok := v.patterns[p]; ok {
+		return c.re, c.err
+	}
+	translated, err := translatePattern(p)
+	var re *regexp.Regexp
+	if err == nil {
+		re, err = regexp.Compile(`^(?:` + translated + `)$`)
+	}
+	v.patterns[p] = compiledPattern{re, err}
+	return re, err
+}
+
+// compiledPattern is a cached pattern facet
+type compiledPattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// nameStartChars and nameChars approximate the XML name character classes
+// see https://www.w3.org/TR/xmlschema-2/#dt-ccesN
+const (
+	nameStartChars = `_:\p{L}\p{Nl}`
+	nameChars      = `\-.` + nameStartChars + `\p{Mn}\p{Mc}\p{Nd}\x{B7}`
+)
+
+// translatePattern replaces the multi-character escapes of XSD that Go doesn't know.
+// Negated escapes can't be expressed inside a character class and are left for the compiler to reject,
+// character class subtraction has no equivalent in Go.
+func translatePattern(p string) (string, error) {
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case c == '\\' && i+1 < len(p):
+			i++
+			chars := ""
+			switch p[i] {
+			case 'i', 'I':
+				chars = nameStartChars
+			case 'c', 'C':
+				chars = nameChars
+			}
+			switch {
+			case chars == "":
+				b.WriteByte(c)
+				b.WriteByte(p[i])
+			case depth > 0 && (p[i] == 'i' || p[i] == 'c'):
+				b.WriteString(chars)
+			case depth > 0:
+				b.WriteByte(c)
+				b.WriteByte(p[i])
+			case p[i] == 'I' || p[i] == 'C':
+				b.WriteString("[^" + chars + "]")
+			default:
+				b.WriteString("[" + chars + "]")
+			}
+		case c == '[' && depth > 0 && i > 0 && p[i-1] == '-':
+			return "", errors.New("character class subtraction is not supported")
+		case c == '[':
+			depth++
+			b.WriteByte(c)
+		case c == ']' && depth > 0:
+			depth--
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+var builtinPatterns = map[string]*regexp.Regexp{
+	"boolean":            regexp.MustCompile(`^(true|false|1|0)$`),
+	"decimal":            regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`),
+	"float":              regexp.MustCompile(`^([+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?|[+-]?INF|NaN)$`),
+	"double":             regexp.MustCompile(`^([+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?|[+-]?INF|NaN)$`),
+	"dateTime":           regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`),
+	"date":               regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}(Z|[+-]\d{2}:\d{2})?$`),
+	"time":               regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`),
+	"duration":           regexp.MustCompile(`^-?P(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`),
+	"gYear":              regexp.MustCompile(`^-?\d{4,}(Z|[+-]\d{2}:\d{2})?$`),
+	"gYearMonth":         regexp.MustCompile(`^-?\d{4,}-\d{2}(Z|[+-]\d{2}:\d{2})?$`),
+	"gMonth":             regexp.MustCompile(`^--\d{2}(Z|[+-]\d{2}:\d{2})?$`),
+	"gMonthDay":          regexp.MustCompile(`^--\d{2}-\d{2}(Z|[+-]\d{2}:\d{2})?$`),
+	"gDay":               regexp.MustCompile(`^---\d{2}(Z|[+-]\d{2}:\d{2})?$`),
+	"hexBinary":          regexp.MustCompile(`^([0-9a-fA-F]{2})*$`),
+	"QName":              regexp.MustCompile(`^([A-Za-z_][\w.\-]*:)?[A-Za-z_][\w.\-]*$`),
+	"NCName":             regexp.MustCompile(`^[A-Za-z_][\w.\-]*$`),
+	"ID":                 regexp.MustCompile(`^[A-Za-z_][\w.\-]*$`),
+	"IDREF":              regexp.MustCompile(`^[A-Za-z_][\w.\-]*$`),
+	"ENTITY":             regexp.MustCompile(`^[A-Za-z_][\w.\-]*$`),
+	"Name":               regexp.MustCompile(`^[A-Za-z_:][\w.\-:]*$`),
+	"NMTOKEN":            regexp.MustCompile(`^[\w.\-:]+$`),
+	"language":           regexp.MustCompile(`^[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*$`),
+	"integer":            regexp.MustCompile(`^[+-]?\d+$`),
+	"nonPositiveInteger": regexp.MustCompile(`^[+-]?\d+$`),
+	"negativeInteger":    regexp.MustCompile(`^[+-]?\d+$`),
+	"nonNegativeInteger": regexp.MustCompile(`^[+-]?\d+$`),
+	"positiveInteger":    regexp.MustCompile(`^[+-]?\d+$`),
+	"long":               regexp.MustCompile(`^[+-]?\d+$`),
+	"int":                regexp.MustCompile(`^[+-]?\d+$`),
+	"short":              regexp.MustCompile(`^[+-]?\d+$`),
+	"byte":               regexp.MustCompile(`^[+-]?\d+$`),
+	"unsignedLong":       regexp.MustCompile(`^[+-]?\d+$`),
+	"unsignedInt":        regexp.MustCompile(`^[+-]?\d+$`),
+	"unsignedShort":      regexp.MustCompile(`^[+-]?\d+$`),
+	"unsignedByte":       regexp.MustCompile(`^[+-]?\d+$`),
+}
+
+// integerRanges are the value spaces of the built-in integer types, nil is unbounded
+var integerRanges = map[string][2]*big.Int{
+	"nonPositiveInteger": {nil, big.NewInt(0)},
+	"negativeInteger":    {nil, big.NewInt(-1)},
+	"nonNegativeInteger": {big.NewInt(0), nil},
+	"positiveInteger":    {big.NewInt(1), nil},
+	"long":               {big.NewInt(-1 << 63), big.NewInt(1<<63 - 1)},
+	"int":                {big.NewInt(-1 << 31), big.NewInt(1<<31 - 1)},
+	"short":              {big.NewInt(-1 << 15), big.NewInt(1<<15 - 1)},
+	"byte":               {big.NewInt(-1 << 7), big.NewInt(1<<7 - 1)},
+	"unsignedLong":       {big.NewInt(0), new(big.Int).SetUint64(1<<64 - 1)},
+	"unsignedInt":        {big.NewInt(0), big.NewInt(1<<32 - 1)},
+	"unsignedShort":      {big.NewInt(0), big.NewInt(1<<16 - 1)},
+	"unsignedByte":       {big.NewInt(0), big.NewInt(1<<8 - 1)},
+}
+
+// checkBuiltin validates the lexical form of a value of a built-in type, unknown types accept any value
+func checkBuiltin(value, typ string) string {
+	if !isStringType(typ) {
+		value = collapseWhitespace(value)
+	}
+	if typ == "base64Binary" {
+		if _, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), "")); err != nil {
+			return fmt.Sprintf("value %q is not a valid %s", value, typ)
+		}
+		return ""
+	}
+	re, ok := builtinPatterns[typ]
+	if !ok {
+		return ""
+	}
+	if !re.MatchString(value) || (typ == "duration" && (strings.HasSuffix(value, "P") || strings.HasSuffix(value, "T"))) {
+		return fmt.Sprintf("value %q is not a valid %s", value, typ)
+	}
+	if r, ok := integerRanges[typ]; ok {
+		n, _ := new(big.Int).SetString(strings.TrimPrefix(value, "+"), 10)
+		if (r[0] != nil && n.Cmp(r[0]) < 0) || (r[1] != nil && n.Cmp(r[1]) > 0) {
+			return fmt.Sprintf("value %s is out of range for %s", value, typ)
+		}
+	}
+	return ""
+}
+
+// isStringType reports whether whitespace is significant for values of a built-in type
+func isStringType(typ string) bool {
+	return typ == "string" || typ == "normalizedString" || typ == "anySimpleType" || typ == ""
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// valueLength returns the length of a value as defined by the length facets of its type
+func valueLength(value, primitive string) int {
+	switch primitive {
+	case "list":
+		return len(strings.Fields(value))
+	case "hexBinary":
+		return len(value) / 2
+	case "base64Binary":
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+		if err != nil {
+			return 0
+		}
+		return len(decoded)
+	}
+	return utf8.RuneCountInString(value)
+}
+
+// compareValues compares two values numerically or as dates, ok is false if they aren't comparable
+func compareValues(a, b, primitive string) (int, bool) {
+	switch primitive {
+	case "dateTime", "date", "time":
+		ta, errA := parseTime(a, timeLayouts[primitive])
+		tb, errB := parseTime(b, timeLayouts[primitive])
+		if errA != nil || errB != nil {
+			return 0, false
+		}
+		return ta.Compare(tb), true
+	}
+	ra, okA := new(big.Rat).SetString(a)
+	rb, okB := new(big.Rat).SetString(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	return ra.Cmp(rb), true
+}
+
+// timeLayouts are the lexical forms of the date and time types, with and without timezone
+var timeLayouts = map[string][]string{
+	"dateTime": {"2006-01-02T15:04:05.999999999Z07:00", "2006-01-02T15:04:05.999999999"},
+	"date":     {"2006-01-02Z07:00", "2006-01-02"},
+	"time":     {"15:04:05.999999999Z07:00", "15:04:05.999999999"},
+}
+
+func parseTime(value string, layouts []string) (time.Time, error) {
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// digits counts the significant total and fraction digits of a decimal value
+func digits(value string) (total, fraction int) {
+	value = strings.TrimLeft(value, "+-")
+	integer, frac, _ := strings.Cut(value, ".")
+	integer = strings.TrimLeft(integer, "0")
+	frac = strings.TrimRight(frac, "0")
+	for _, s := range []string{integer, frac} {
+		for _, r := range s {
+			if r < '0' || r > '9' {
+				return 0, 0
+			}
+		}
+	}
+	return len(integer) + len(frac), len(frac)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+	return strings.Join(quoted, ", ")
+}
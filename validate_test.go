@@ -0,0 +1,266 @@
+package gosoap
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderItem struct {
+	SKU      string `xml:"sku,attr,omitempty"`
+	Quantity string `xml:"Quantity"`
+}
+
+type placeOrder struct {
+	XMLName  xml.Name    `xml:"http://example.com/order PlaceOrder"`
+	Customer string      `xml:"Customer,omitempty"`
+	Status   string      `xml:"Status"`
+	Items    []orderItem `xml:"Item"`
+	Note     string      `xml:"Note,omitempty"`
+}
+
+func TestValidateRequests(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name       string
+		body       placeOrder
+		violations []Violation
+	}{
+		{
+			name: "valid",
+			body: placeOrder{Customer: "ABC1234", Status: "new", Items: []orderItem{{SKU: "x", Quantity: "1"}}, Note: "fragile"},
+		},
+		{
+			name: "missing required element",
+			body: placeOrder{Status: "paid", Items: []orderItem{{SKU: "x", Quantity: "1"}}},
+			violations: []Violation{
+				{Path: "/PlaceOrder", Message: `missing element "Customer"`},
+			},
+		},
+		{
+			name: "facets",
+			body: placeOrder{Customer: "abc", Status: "shipped", Items: []orderItem{
+				{SKU: "x", Quantity: "0"},
+				{Quantity: "ten"},
+			}, Note: "handle with care"},
+			violations: []Violation{
+				{Path: "/PlaceOrder/Customer", Message: `value "abc" does not match the pattern "[A-Z]{3}\\d{4}"`},
+				{Path: "/PlaceOrder/Status", Message: `value "shipped" is not one of "new", "paid"`},
+				{Path: "/PlaceOrder/Item[1]/Quantity", Message: `value 0 is less than 1`},
+				{Path: "/PlaceOrder/Item[2]", Message: `missing attribute "sku"`},
+				{Path: "/PlaceOrder/Item[2]/Quantity", Message: `value "ten" is not a valid int`},
+				{Path: "/PlaceOrder/Note", Message: `length 16 violates maxLength 10`},
+			},
+		},
+		{
+			name: "cardinality",
+			body: placeOrder{Customer: "ABC1234", Status: "new", Items: []orderItem{
+				{SKU: "a", Quantity: "1"}, {SKU: "b", Quantity: "1"}, {SKU: "c", Quantity: "1"}, {SKU: "d", Quantity: "1"},
+			}},
+			violations: []Violation{
+				{Path: "/PlaceOrder/Item[4]", Message: `unexpected element "Item"`},
+			},
+		},
+	}
+
+	server, reqBody := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/order.wsdl", server, Config{ValidateRequests: true})
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			*reqBody = nil
+			_, err := client.Call(context.Background(), "PlaceOrder", tc.body)
+			if tc.violations == nil {
+				require.NoError(t, err)
+				assert.NotEmpty(t, *reqBody)
+				return
+			}
+			var verr *ValidationError
+			require.True(t, errors.As(err, &verr), "expected a validation error, got %v", err)
+			assert.Equal(t, tc.violations, verr.Violations)
+			assert.Nil(t, *reqBody, "invalid requests must not be sent")
+		})
+	}
+}
+
+func TestValidator(t *testing.T) {
+	t.Parallel()
+	d, err := getWSDLDefinitions(context.Background(), SourceFromBytes([]byte(schemaTestWSDL)), &Config{})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		element    string
+		xml        string
+		violations []Violation
+	}{
+		{
+			name:    "extension with group and substitution",
+			element: "Customer",
+			xml:     `<Customer xmlns="urn:shop" createdBy="me" vip="true"><Name>A</Name><Phone>1</Phone><Card>4111</Card><Cash currency="EUR">1.50</Cash></Customer>`,
+		},
+		{
+			name:    "choice and attributes",
+			element: "Customer",
+			xml:     `<Customer xmlns="urn:shop" vip="maybe" color="red"><Name>A</Name><Cash>-1.505</Cash></Customer>`,
+			violations: []Violation{
+				{Path: "/Customer/@vip", Message: `value "maybe" is not a valid boolean`},
+				{Path: "/Customer", Message: `missing attribute "createdBy"`},
+				{Path: "/Customer/@color", Message: `unexpected attribute`},
+				{Path: "/Customer", Message: `missing one of the elements "Email", "Phone"`},
+				{Path: "/Customer/Cash", Message: `value -1.505 is less than 0`},
+			},
+		},
+		{
+			name:    "abstract element",
+			element: "Customer",
+			xml:     `<Customer xmlns="urn:shop" createdBy="me"><Name>A</Name><Email>a@b</Email><Payment/></Customer>`,
+			violations: []Violation{
+				{Path: "/Customer/Payment", Message: `unexpected element "Payment"`},
+			},
+		},
+		{
+			name:    "all with wildcard",
+			element: "Note",
+			xml:     `<Note xmlns="urn:shop"><x:Extra xmlns:x="urn:other"/><Text xmlns="">hi</Text></Note>`,
+		},
+		{
+			name:    "all missing element",
+			element: "Note",
+			xml:     `<Note xmlns="urn:shop"></Note>`,
+			violations: []Violation{
+				{Path: "/Note", Message: `missing element "Text"`},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			nodes, err := parseXMLNodes([]byte(tc.xml))
+			require.NoError(t, err)
+			msg := &wsdlMessage{Parts: []*wsdlMessagePart{{Name: "parameters", Element: "tns:" + tc.element, ElementName: shopName(tc.element)}}}
+			verr := d.validateMessage(nodes, nil, msg, nil, false)
+			if tc.violations == nil {
+				assert.Nil(t, verr)
+				return
+			}
+			require.NotNil(t, verr)
+			assert.Equal(t, tc.violations, verr.Violations)
+		})
+	}
+}
+
+func TestValidateFixedAttributes(t *testing.T) {
+	t.Parallel()
+	d, err := getWSDLDefinitions(context.Background(), SourceFromBytes([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/" xmlns:tns="urn:shop" xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:shop">
+  <wsdl:types>
+    <xs:schema targetNamespace="urn:shop" elementFormDefault="qualified">
+      <xs:attribute name="version" type="xs:string" fixed="1.0"/>
+      <xs:attribute name="schema" type="xs:string" fixed="v1"/>
+      <xs:element name="Order">
+        <xs:complexType>
+          <xs:attribute ref="tns:version" fixed="1.0"/>
+          <xs:attribute ref="tns:schema"/>
+        </xs:complexType>
+      </xs:element>
+    </xs:schema>
+  </wsdl:types>
+</wsdl:definitions>`)), &Config{})
+	require.NoError(t, err)
+	msg := &wsdlMessage{Parts: []*wsdlMessagePart{{Name: "parameters", Element: "tns:Order", ElementName: shopName("Order")}}}
+
+	nodes, err := parseXMLNodes([]byte(`<Order xmlns="urn:shop" xmlns:s="urn:shop" s:version="1.0" s:schema="v1"/>`))
+	require.NoError(t, err)
+	assert.Nil(t, d.validateMessage(nodes, nil, msg, nil, false))
+
+	nodes, err = parseXMLNodes([]byte(`<Order xmlns="urn:shop" xmlns:s="urn:shop" s:version="2.0" s:schema="v2"/>`))
+	require.NoError(t, err)
+	verr := d.validateMessage(nodes, nil, msg, nil, false)
+	require.NotNil(t, verr)
+	assert.Equal(t, []Violation{
+		{Path: "/Order/@version", Message: `value must be "1.0"`},
+		{Path: "/Order/@schema", Message: `value must be "v1"`},
+	}, verr.Violations)
+}
+
+func TestValidateResponses(t *testing.T) {
+	t.Parallel()
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:o="http://example.com/order"><soap:Body>`+response+`</soap:Body></soap:Envelope>`)
+	}))
+	t.Cleanup(server.Close)
+	request := placeOrder{Customer: "ABC1234", Status: "new", Items: []orderItem{{SKU: "x", Quantity: "1"}}}
+	invalid := `<o:PlaceOrderResponse><o:Total>abc</o:Total><o:Discount>1</o:Discount></o:PlaceOrderResponse>`
+	violations := []Violation{
+		{Path: "/PlaceOrderResponse", Message: `missing element "OrderID"`},
+		{Path: "/PlaceOrderResponse/Total", Message: `value "abc" is not a valid decimal`},
+		{Path: "/PlaceOrderResponse/Discount", Message: `unexpected element "Discount"`},
+	}
+
+	t.Run("error", func(t *testing.T) {
+		client := newTestClient(t, "./testdata/order.wsdl", server, Config{ValidateResponses: true})
+
+		response = `<o:PlaceOrderResponse><o:OrderID>42</o:OrderID></o:PlaceOrderResponse>`
+		_, err := client.Call(context.Background(), "PlaceOrder", request)
+		require.NoError(t, err)
+
+		response = `<soap:Fault><faultcode>soap:Server</faultcode><faultstring>boom</faultstring></soap:Fault>`
+		res, err := client.Call(context.Background(), "PlaceOrder", request)
+		require.NoError(t, err)
+		assert.ErrorAs(t, res.Unmarshal(&struct{}{}), &FaultError{})
+
+		response = invalid
+		res, err = client.Call(context.Background(), "PlaceOrder", request)
+		var verr *ValidationError
+		require.True(t, errors.As(err, &verr), "expected a validation error, got %v", err)
+		assert.Equal(t, violations, verr.Violations)
+		require.NotNil(t, res, "the response is returned with the validation error")
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		var reported []*ValidationError
+		client := newTestClient(t, "./testdata/order.wsdl", server, Config{
+			ValidateResponses: true,
+			OnResponseViolations: func(operation string, err *ValidationError) {
+				assert.Equal(t, "PlaceOrder", operation)
+				reported = append(reported, err)
+			},
+		})
+		response = invalid
+		_, err := client.Call(context.Background(), "PlaceOrder", request)
+		require.NoError(t, err)
+		require.Len(t, reported, 1)
+		assert.Equal(t, violations, reported[0].Violations)
+	})
+}
+
+func TestPatternFacets(t *testing.T) {
+	t.Parallel()
+	v := &validator{patterns: map[string]compiledPattern{}}
+	pattern := func(p string) xsdFacets {
+		return xsdFacets{{XMLName: xml.Name{Space: xsdNS, Local: "pattern"}, Value: p}}
+	}
+	cases := []struct {
+		pattern string
+		value   string
+		msg     string
+	}{
+		{pattern: `\i\c*`, value: "Straße"},
+		{pattern: `\i\c*`, value: "名前-1"},
+		{pattern: `\i\c*`, value: "1abc", msg: `value "1abc" does not match the pattern "\\i\\c*"`},
+		{pattern: `[\i\d]+`, value: "é1"},
+		{pattern: `\I+`, value: "12"},
+		{pattern: `[a-z-[aeiou]]+`, value: "xyz", msg: `the pattern "[a-z-[aeiou]]+" can't be checked: character class subtraction is not supported`},
+		{pattern: `\p{IsBasicLatin}+`, value: "abc", msg: "the pattern \"\\\\p{IsBasicLatin}+\" can't be checked: error parsing regexp: invalid character class range: `\\p{IsBasicLatin}`"},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.msg, v.checkFacets(tc.value, pattern(tc.pattern), "string"), tc.pattern)
+	}
+}
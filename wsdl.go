@@ -1,17 +1,15 @@
 package gosoap
 
 import (
-	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"os"
-
-	"golang.org/x/net/html/charset"
+	"strings"
 )
 
+// wsdlNS is the namespace of WSDL 1.1 documents
+const wsdlNS = "http://schemas.xmlsoap.org/wsdl/"
+
 type wsdlDefinitions struct {
 	Name            string        `xml:"name,attr"`
 	TargetNamespace string        `xml:"targetNamespace,attr"`
@@ -22,6 +20,14 @@ type wsdlDefinitions struct {
 	PortTypes []*wsdlPortTypes `xml:"http://schemas.xmlsoap.org/wsdl/ portType"`
 	Services  []*wsdlService   `xml:"http://schemas.xmlsoap.org/wsdl/ service"`
 	Bindings  []*wsdlBinding   `xml:"http://schemas.xmlsoap.org/wsdl/ binding"`
+	// namespace declarations on the definitions element, used to resolve QName attributes
+	Attrs []xml.Attr `xml:",any,attr"`
+
+	// indexes built by compile once the WSDL and its imports are loaded
+	schemas   *xsdSchemaSet
+	messages  map[xml.Name]*wsdlMessage
+	portTypes map[xml.Name]*wsdlPortTypes
+	bindings  map[xml.Name]*wsdlBinding
 }
 
 type wsdlBinding struct {
@@ -29,16 +35,31 @@ type wsdlBinding struct {
 	Type         string           `xml:"type,attr"`
 	Operations   []*wsdlOperation `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
 	SoapBindings []*soapBinding   `xml:"http://schemas.xmlsoap.org/wsdl/soap/ binding"`
+
+	QName    xml.Name `xml:"-"`
+	TypeName xml.Name `xml:"-"`
+
+	operations map[string][]*wsdlOperation
 }
 
 type soapBinding struct {
 	Transport string `xml:"transport,attr"`
+	Style     string `xml:"style,attr"`
 }
 
 type wsdlTypes struct {
 	XsdSchema []*xsdSchema `xml:"http://www.w3.org/2001/XMLSchema schema"`
 }
 
+// allSchemas returns the schemas of all types sections
+func (d *wsdlDefinitions) allSchemas() []*xsdSchema {
+	var schemas []*xsdSchema
+	for _, t := range d.Types {
+		schemas = append(schemas, t.XsdSchema...)
+	}
+	return schemas
+}
+
 type wsdlImport struct {
 	Namespace string `xml:"namespace,attr"`
 	Location  string `xml:"location,attr"`
@@ -47,16 +68,41 @@ type wsdlImport struct {
 type wsdlMessage struct {
 	Name  string             `xml:"name,attr"`
 	Parts []*wsdlMessagePart `xml:"http://schemas.xmlsoap.org/wsdl/ part"`
+
+	QName xml.Name `xml:"-"`
 }
 
 type wsdlMessagePart struct {
 	Name    string `xml:"name,attr"`
 	Element string `xml:"element,attr"`
+	Type    string `xml:"type,attr"`
+
+	// Element and Type resolved with the namespaces of the document declaring the message
+	ElementName xml.Name `xml:"-"`
+	TypeName    xml.Name `xml:"-"`
+}
+
+// checkNames returns an error if the prefix of the element or type of the part isn't declared,
+// a prefixed QName can't resolve to the empty namespace otherwise
+func (p *wsdlMessagePart) checkNames() error {
+	for _, ref := range []struct {
+		qname string
+		name  xml.Name
+	}{{p.Element, p.ElementName}, {p.Type, p.TypeName}} {
+		if prefix, _, found := strings.Cut(ref.qname, ":"); found && ref.name.Space == "" {
+			return fmt.Errorf("message part %q references %q with the undeclared namespace prefix %q", p.Name, ref.qname, prefix)
+		}
+	}
+	return nil
 }
 
 type wsdlPortTypes struct {
 	Name       string           `xml:"name,attr"`
 	Operations []*wsdlOperation `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
+
+	QName xml.Name `xml:"-"`
+
+	operations map[string][]*wsdlOperation
 }
 
 type wsdlOperation struct {
@@ -68,19 +114,31 @@ type wsdlOperation struct {
 }
 
 type wsdlOperationInput struct {
-	Message    string `xml:"message,attr"`
-	WsawAction string `xml:"http://www.w3.org/2006/05/addressing/wsdl Action,attr"`
+	Name        string        `xml:"name,attr"`
+	Message     string        `xml:"message,attr"`
+	WsawAction  string        `xml:"http://www.w3.org/2006/05/addressing/wsdl Action,attr"`
+	SoapBodies  []*soapBody   `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
+	SoapHeaders []*soapHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap/ header"`
+
+	MessageName xml.Name `xml:"-"`
 }
 
 type wsdlOperationOutput struct {
-	Message    string `xml:"message,attr"`
-	WsawAction string `xml:"http://www.w3.org/2006/05/addressing/wsdl Action,attr"`
+	Name        string        `xml:"name,attr"`
+	Message     string        `xml:"message,attr"`
+	WsawAction  string        `xml:"http://www.w3.org/2006/05/addressing/wsdl Action,attr"`
+	SoapBodies  []*soapBody   `xml:"http://schemas.xmlsoap.org/wsdl/soap/ body"`
+	SoapHeaders []*soapHeader `xml:"http://schemas.xmlsoap.org/wsdl/soap/ header"`
+
+	MessageName xml.Name `xml:"-"`
 }
 
 type wsdlOperationFault struct {
 	Name       string `xml:"name,attr"`
 	Message    string `xml:"message,attr"`
 	WsawAction string `xml:"http://www.w3.org/2006/05/addressing/wsdl Action,attr"`
+
+	MessageName xml.Name `xml:"-"`
 }
 
 type wsdlService struct {
@@ -92,143 +150,364 @@ type wsdlPort struct {
 	Name          string         `xml:"name,attr"`
 	Binding       string         `xml:"binding,attr"`
 	SoapAddresses []*soapAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap/ address"`
+
+	BindingName xml.Name `xml:"-"`
+	// Unsupported is the reason a port of a WSDL 2.0 document has no SOAP address although it has an address
+	Unsupported string `xml:"-"`
 }
 
 type soapAddress struct {
 	Location string `xml:"location,attr"`
 }
 
+// see https://www.w3.org/TR/2001/NOTE-wsdl-20010315#_soap:body
+type soapBody struct {
+	// space separated list of the message parts that appear in the body, all parts if empty
+	Parts         string `xml:"parts,attr"`
+	Use           string `xml:"use,attr"`
+	EncodingStyle string `xml:"encodingStyle,attr"`
+	Namespace     string `xml:"namespace,attr"`
+}
+
+// includes reports whether a message part is part of the body
+func (b *soapBody) includes(part string) bool {
+	if b == nil || b.Parts == "" {
+		return true
+	}
+	for _, p := range strings.Fields(b.Parts) {
+		if p == part {
+			return true
+		}
+	}
+	return false
+}
+
+// see https://www.w3.org/TR/2001/NOTE-wsdl-20010315#_soap:header
+type soapHeader struct {
+	Message       string `xml:"message,attr"`
+	Part          string `xml:"part,attr"`
+	Use           string `xml:"use,attr"`
+	EncodingStyle string `xml:"encodingStyle,attr"`
+	Namespace     string `xml:"namespace,attr"`
+
+	MessageName xml.Name `xml:"-"`
+}
+
 type soapOperation struct {
 	SoapAction string `xml:"soapAction,attr"`
 	Style      string `xml:"style,attr"`
 }
 
-type xsdSchema struct {
-	TargetNamespace    string            `xml:"targetNamespace,attr"`
-	ElementFormDefault string            `xml:"elementFormDefault,attr"`
-	Imports            []*xsdImport      `xml:"http://www.w3.org/2001/XMLSchema import"`
-	Elements           []*xsdElement     `xml:"http://www.w3.org/2001/XMLSchema element"`
-	ComplexTypes       []*xsdComplexType `xml:"http://www.w3.org/2001/XMLSchema complexType"`
+// GetSoapActionFromWsdlOperation the SoapAction of an operation might differ from the action wsdl-operation name
+// if any SoapAction name is set in the wsdlOperation binding, use that.
+func (b *wsdlBinding) GetSoapActionFromWsdlOperation(operation string) (string, error) {
+	if b == nil {
+		return "", fmt.Errorf("WSDL binding is nil")
+	}
+	o, err := b.operation(operation, "", "")
+	if err != nil {
+		return "", err
+	}
+	return b.soapAction(o, operation)
 }
 
-type xsdImport struct {
-	SchemaLocation string `xml:"schemaLocation,attr"`
-	Namespace      string `xml:"namespace,attr"`
+func (b *wsdlBinding) soapAction(o *wsdlOperation, operation string) (string, error) {
+	if o != nil && len(o.SoapOperations) > 0 {
+		return o.SoapOperations[0].SoapAction, nil
+	}
+	return "", fmt.Errorf("could not find operating matching %q in binding %q", operation, b.Name)
 }
 
-type xsdElement struct {
-	Name        string          `xml:"name,attr"`
-	Nillable    bool            `xml:"nillable,attr"`
-	Type        string          `xml:"type,attr"`
-	MinOccurs   string          `xml:"minOccurs,attr"`
-	MaxOccurs   string          `xml:"maxOccurs,attr"`
-	ComplexType *xsdComplexType `xml:"http://www.w3.org/2001/XMLSchema complexType"`
-	SimpleType  *xsdSimpleType  `xml:"http://www.w3.org/2001/XMLSchema simpleType"`
+// namespaces are the namespace declarations in scope of an element, later declarations take precedence
+type namespaces []xml.Attr
+
+// resolve resolves a prefixed name like "tns:Foo", unprefixed names are resolved against the default namespace.
+func (ns namespaces) resolve(qname string) xml.Name {
+	prefix, local, found := strings.Cut(qname, ":")
+	if !found {
+		prefix, local = "", qname
+	}
+	for i := len(ns) - 1; i >= 0; i-- {
+		attr := ns[i]
+		if prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			return xml.Name{Space: attr.Value, Local: local}
+		}
+		if prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix {
+			return xml.Name{Space: attr.Value, Local: local}
+		}
+	}
+	if prefix == "xml" {
+		return xml.Name{Space: xmlNS, Local: local}
+	}
+	return xml.Name{Local: local}
 }
 
-type xsdComplexType struct {
-	Name     string       `xml:"name,attr"`
-	Sequence *xsdSequence `xml:"http://www.w3.org/2001/XMLSchema sequence"`
+// resolveQName resolves a prefixed name using the namespaces declared on the definitions element.
+func (d *wsdlDefinitions) resolveQName(qname string) xml.Name {
+	return namespaces(d.Attrs).resolve(qname)
 }
 
-type xsdSimpleType struct {
-	Name     string          `xml:"name,attr"`
-	Sequence *xsdRestriction `xml:"http://www.w3.org/2001/XMLSchema restriction"`
+// portType looks up a port type by name, names that couldn't be resolved to the right namespace fall back to
+// matching the local name.
+func (d *wsdlDefinitions) portType(name xml.Name) *wsdlPortTypes {
+	if pt, ok := d.portTypes[name]; ok {
+		return pt
+	}
+	for _, pt := range d.PortTypes {
+		if pt.Name == name.Local {
+			return pt
+		}
+	}
+	return nil
+}
+
+// message looks up a message by name like portType
+func (d *wsdlDefinitions) message(name xml.Name) *wsdlMessage {
+	if m, ok := d.messages[name]; ok {
+		return m
+	}
+	for _, m := range d.Messages {
+		if m.Name == name.Local {
+			return m
+		}
+	}
+	return nil
 }
 
-type xsdSequence struct {
-	Elements []*xsdElement `xml:"http://www.w3.org/2001/XMLSchema element"`
+// binding looks up a binding by name like portType
+func (d *wsdlDefinitions) binding(name xml.Name) *wsdlBinding {
+	if b, ok := d.bindings[name]; ok {
+		return b
+	}
+	for _, b := range d.Bindings {
+		if b.Name == name.Local {
+			return b
+		}
+	}
+	return nil
 }
 
-type xsdRestriction struct {
-	Base         string           `xml:"base,attr"`
-	Pattern      *xsdPattern      `xml:"http://www.w3.org/2001/XMLSchema pattern"`
-	MinInclusive *xsdMinInclusive `xml:"http://www.w3.org/2001/XMLSchema minInclusive"`
-	MaxInclusive *xsdMaxInclusive `xml:"http://www.w3.org/2001/XMLSchema maxInclusive"`
+// abstractOperation looks up the port type operation of a binding operation through the port type of the binding
+func (d *wsdlDefinitions) abstractOperation(b *wsdlBinding, bindingOperation *wsdlOperation) (*wsdlOperation, error) {
+	if bindingOperation == nil {
+		return nil, errors.New("operation is not bound")
+	}
+	pt := d.portType(b.TypeName)
+	if pt == nil {
+		return nil, fmt.Errorf("could not find port type %q of binding %q", b.Type, b.Name)
+	}
+	input, output := bindingOperation.declaredNames()
+	o, err := pt.operation(bindingOperation.Name, input, output)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		return nil, fmt.Errorf("could not find operation %q in port type %q", bindingOperation.Name, pt.Name)
+	}
+	return o, nil
 }
 
-type xsdPattern struct {
-	Value string `xml:"value,attr"`
+// inputMessage looks up the input message of a binding operation
+func (d *wsdlDefinitions) inputMessage(b *wsdlBinding, bindingOperation *wsdlOperation) (*wsdlMessage, error) {
+	o, err := d.abstractOperation(b, bindingOperation)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.Inputs) == 0 {
+		return nil, fmt.Errorf("operation %q has no input", o.Name)
+	}
+	m := d.message(o.Inputs[0].MessageName)
+	if m == nil {
+		return nil, fmt.Errorf("could not find message %q of operation %q", o.Inputs[0].Message, o.Name)
+	}
+	return m, nil
 }
 
-type xsdMinInclusive struct {
-	Value string `xml:"value,attr"`
+// outputMessage looks up the output message of a binding operation
+func (d *wsdlDefinitions) outputMessage(b *wsdlBinding, bindingOperation *wsdlOperation) (*wsdlMessage, error) {
+	o, err := d.abstractOperation(b, bindingOperation)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.Outputs) == 0 {
+		return nil, fmt.Errorf("operation %q has no output", o.Name)
+	}
+	m := d.message(o.Outputs[0].MessageName)
+	if m == nil {
+		return nil, fmt.Errorf("could not find message %q of operation %q", o.Outputs[0].Message, o.Name)
+	}
+	return m, nil
 }
 
-type xsdMaxInclusive struct {
-	Value string `xml:"value,attr"`
+// operation looks up an operation of the binding, input and output select one of overloaded operations.
+// It returns nil if no operation matches and an error listing the candidates if several do.
+func (b *wsdlBinding) operation(name, input, output string) (*wsdlOperation, error) {
+	if b == nil {
+		return nil, errors.New("WSDL binding is nil")
+	}
+	return selectOperation(b.operations, b.Operations, name, input, output)
 }
 
-type WSDLSource func(config *Config) ([]byte, error)
+func (pt *wsdlPortTypes) operation(name, input, output string) (*wsdlOperation, error) {
+	return selectOperation(pt.operations, pt.Operations, name, input, output)
+}
 
-func SourceFromURI(uri string) WSDLSource {
-	return func(config *Config) ([]byte, error) {
-		res, err := getWsdlBody(uri, config.Client)
-		if err != nil {
-			return nil, fmt.Errorf("could not fetch WSDL resource: %w", err)
+// selectOperation looks up an operation in the index, definitions that were not compiled are scanned
+func selectOperation(index map[string][]*wsdlOperation, operations []*wsdlOperation, name, input, output string) (*wsdlOperation, error) {
+	if index != nil {
+		operations = index[name]
+	}
+	var candidates []*wsdlOperation
+	for _, o := range operations {
+		if o.Name == name && o.matches(input, output) {
+			candidates = append(candidates, o)
 		}
-		defer res.Close()
-		return io.ReadAll(res)
 	}
+	switch len(candidates) {
+	case 0:
+		return nil, nil
+	case 1:
+		return candidates[0], nil
+	}
+	descriptions := make([]string, 0, len(candidates))
+	for _, o := range candidates {
+		descriptions = append(descriptions, fmt.Sprintf("input %q output %q", o.inputName(), o.outputName()))
+	}
+	return nil, fmt.Errorf("operation %q is ambiguous, set the input or output name of the request to select one of: %s",
+		name, strings.Join(descriptions, ", "))
 }
 
-func SourceFromBytes(raw []byte) WSDLSource {
-	return func(config *Config) ([]byte, error) {
-		return raw, nil
+// indexOperations maps operations by name, overloaded operations share a name
+func indexOperations(operations []*wsdlOperation) map[string][]*wsdlOperation {
+	index := make(map[string][]*wsdlOperation, len(operations))
+	for _, o := range operations {
+		index[o.Name] = append(index[o.Name], o)
 	}
+	return index
+}
+
+// inputName returns the name of the input of an operation, the default is derived from the operation name.
+// see https://www.w3.org/TR/2001/NOTE-wsdl-20010315#_names
+func (o *wsdlOperation) inputName() string {
+	switch {
+	case len(o.Inputs) == 0:
+		return ""
+	case o.Inputs[0].Name != "":
+		return o.Inputs[0].Name
+	case len(o.Outputs) == 0:
+		return o.Name
+	}
+	return o.Name + "Request"
+}
+
+// outputName returns the name of the output of an operation like inputName
+func (o *wsdlOperation) outputName() string {
+	switch {
+	case len(o.Outputs) == 0:
+		return ""
+	case o.Outputs[0].Name != "":
+		return o.Outputs[0].Name
+	case len(o.Inputs) == 0:
+		return o.Name
+	}
+	return o.Name + "Response"
 }
 
-func getWsdlBody(u string, c *http.Client) (reader io.ReadCloser, err error) {
-	parse, err := url.Parse(u)
-	if err != nil {
-		return nil, err
+// declaredNames returns the input and output names set in the document, empty if they are implied
+func (o *wsdlOperation) declaredNames() (input, output string) {
+	if len(o.Inputs) > 0 {
+		input = o.Inputs[0].Name
+	}
+	if len(o.Outputs) > 0 {
+		output = o.Outputs[0].Name
 	}
-	if parse.Scheme == "file" {
-		outFile, err := os.Open(parse.Path)
-		if err != nil {
-			return nil, err
+	return input, output
+}
+
+// matches reports whether the input and output names of an operation match, empty names match any operation
+func (o *wsdlOperation) matches(input, output string) bool {
+	return (input == "" || o.inputName() == input) && (output == "" || o.outputName() == output)
+}
+
+// compile builds the indexes of the definitions, it is called once the WSDL and its imports are loaded
+// or a snapshot is restored.
+func (d *wsdlDefinitions) compile() {
+	d.schemas = newSchemaSet(d.allSchemas())
+	d.messages = make(map[xml.Name]*wsdlMessage, len(d.Messages))
+	for _, m := range d.Messages {
+		if _, ok := d.messages[m.QName]; !ok {
+			d.messages[m.QName] = m
 		}
-		return outFile, nil
 	}
-	if c == nil {
-		c = &http.Client{}
+	d.portTypes = make(map[xml.Name]*wsdlPortTypes, len(d.PortTypes))
+	for _, pt := range d.PortTypes {
+		pt.operations = indexOperations(pt.Operations)
+		if _, ok := d.portTypes[pt.QName]; !ok {
+			d.portTypes[pt.QName] = pt
+		}
 	}
-	r, err := c.Get(u)
-	if err != nil {
-		return nil, err
+	d.bindings = make(map[xml.Name]*wsdlBinding, len(d.Bindings))
+	for _, b := range d.Bindings {
+		b.operations = indexOperations(b.Operations)
+		if _, ok := d.bindings[b.QName]; !ok {
+			d.bindings[b.QName] = b
+		}
 	}
-	return r.Body, nil
 }
 
-// getWSDLDefinitions sent request to the wsdl url and set definitions on struct
-func getWSDLDefinitions(source WSDLSource, c *Config) (wsdl *wsdlDefinitions, err error) {
-	raw, err := source(c)
-	if err != nil {
-		return nil, fmt.Errorf("could not load WSDL: %w", err)
+// inputBody returns the soap:body of the input of a binding operation, or nil if it has none
+func inputBody(o *wsdlOperation) *soapBody {
+	if o == nil || len(o.Inputs) == 0 || len(o.Inputs[0].SoapBodies) == 0 {
+		return nil
 	}
-	decoder := xml.NewDecoder(bytes.NewReader(raw))
-	decoder.CharsetReader = charset.NewReaderLabel
-	err = decoder.Decode(&wsdl)
+	return o.Inputs[0].SoapBodies[0]
+}
 
-	return wsdl, err
+// outputBody returns the soap:body of the output of a binding operation
+func outputBody(o *wsdlOperation) *soapBody {
+	if o == nil || len(o.Outputs) == 0 || len(o.Outputs[0].SoapBodies) == 0 {
+		return nil
+	}
+	return o.Outputs[0].SoapBodies[0]
 }
 
-// GetSoapActionFromWsdlOperation the SoapAction of an operation might differ from the action wsdl-operation name
-// if any SoapAction name is set in the wsdlOperation binding, use that.
-func (b *wsdlBinding) GetSoapActionFromWsdlOperation(operation string) (string, error) {
-	if b == nil {
-		return "", fmt.Errorf("WSDL binding is nil")
+// inputHeaders returns the soap:header declarations of the input of a binding operation
+func inputHeaders(o *wsdlOperation) []*soapHeader {
+	if o == nil || len(o.Inputs) == 0 {
+		return nil
+	}
+	return o.Inputs[0].SoapHeaders
+}
+
+// headerName returns the name of the header block declared by a soap:header.
+// Element parts use the element name, type parts are named after the part.
+func (d *wsdlDefinitions) headerName(h *soapHeader) (xml.Name, error) {
+	m := d.message(h.MessageName)
+	if m == nil {
+		return xml.Name{}, fmt.Errorf("could not find message %q of SOAP header", h.Message)
 	}
-	// in the future it would be nice to have Operations be map[string]*wsdlOperation,
-	// where the map key is the wsdlOperation name
-	for _, o := range b.Operations {
-		if o.Name == operation {
-			if len(o.SoapOperations) > 0 {
-				return o.SoapOperations[0].SoapAction, nil
-			}
+	for _, p := range m.Parts {
+		if p.Name != h.Part {
+			continue
 		}
+		if p.Element != "" {
+			return p.ElementName, nil
+		}
+		return xml.Name{Space: h.Namespace, Local: p.Name}, nil
 	}
-	return "", fmt.Errorf("could not find operating matching %q in binding %q", operation, b.Name)
+	return xml.Name{}, fmt.Errorf("could not find part %q in message %q of SOAP header", h.Part, h.Message)
+}
+
+// style returns the SOAP style of an operation, falling back to the style of the binding.
+// see https://www.w3.org/TR/2001/NOTE-wsdl-20010315#_soap:operation
+func (b *wsdlBinding) style(o *wsdlOperation) string {
+	if o != nil && len(o.SoapOperations) > 0 && o.SoapOperations[0].Style != "" {
+		return o.SoapOperations[0].Style
+	}
+	if len(b.SoapBindings) > 0 && b.SoapBindings[0].Style != "" {
+		return b.SoapBindings[0].Style
+	}
+	return "document"
 }
 
 // Fault see https://www.w3.org/TR/2000/NOTE-SOAP-20000508/#_Toc478383507
@@ -0,0 +1,357 @@
+package gosoap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// WSDL 2.0 documents are mapped onto the WSDL 1.1 model used by the client,
+// interfaces become port types, endpoints become ports and the element of every
+// input, output and fault becomes a message with a single part.
+// The client only sends SOAP 1.1 envelopes, so only SOAP bindings with wsoap:version="1.1" can be used,
+// bindings without a version default to SOAP 1.2.
+// see https://www.w3.org/TR/wsdl20/
+const (
+	wsdl2NS = "http://www.w3.org/ns/wsdl"
+	wsoapNS = "http://www.w3.org/ns/wsdl/soap"
+	// wsdl2Part is the name of the part that carries the element of a message
+	wsdl2Part = "parameters"
+	// wsoapVersion is the SOAP version of a binding without a wsoap:version
+	// see https://www.w3.org/TR/wsdl20-adjuncts/#soap-version-decl
+	wsoapVersion = "1.2"
+)
+
+type wsdl2Description struct {
+	TargetNamespace string            `xml:"targetNamespace,attr"`
+	Imports         []*wsdlImport     `xml:"http://www.w3.org/ns/wsdl import"`
+	Includes        []*wsdlImport     `xml:"http://www.w3.org/ns/wsdl include"`
+	Types           []*wsdlTypes      `xml:"http://www.w3.org/ns/wsdl types"`
+	Interfaces      []*wsdl2Interface `xml:"http://www.w3.org/ns/wsdl interface"`
+	Bindings        []*wsdl2Binding   `xml:"http://www.w3.org/ns/wsdl binding"`
+	Services        []*wsdl2Service   `xml:"http://www.w3.org/ns/wsdl service"`
+	Attrs           []xml.Attr        `xml:",any,attr"`
+}
+
+// see https://www.w3.org/TR/wsdl20/#Interface_XMLRep
+type wsdl2Interface struct {
+	Name string `xml:"name,attr"`
+	// space separated list of the interfaces whose operations and faults are inherited
+	Extends    string                     `xml:"extends,attr"`
+	Faults     []*wsdl2InterfaceFault     `xml:"http://www.w3.org/ns/wsdl fault"`
+	Operations []*wsdl2InterfaceOperation `xml:"http://www.w3.org/ns/wsdl operation"`
+}
+
+type wsdl2InterfaceFault struct {
+	Name    string `xml:"name,attr"`
+	Element string `xml:"element,attr"`
+}
+
+type wsdl2InterfaceOperation struct {
+	Name      string             `xml:"name,attr"`
+	Pattern   string             `xml:"pattern,attr"`
+	Inputs    []*wsdl2MessageRef `xml:"http://www.w3.org/ns/wsdl input"`
+	Outputs   []*wsdl2MessageRef `xml:"http://www.w3.org/ns/wsdl output"`
+	OutFaults []*wsdl2FaultRef   `xml:"http://www.w3.org/ns/wsdl outfault"`
+}
+
+// see https://www.w3.org/TR/wsdl20/#InterfaceMessageReference_XMLRep
+type wsdl2MessageRef struct {
+	MessageLabel string `xml:"messageLabel,attr"`
+	// QName of the element or one of the tokens #any, #none and #other
+	Element string `xml:"element,attr"`
+	Action  string `xml:"http://www.w3.org/2007/05/addressing/metadata Action,attr"`
+}
+
+type wsdl2FaultRef struct {
+	Ref    string `xml:"ref,attr"`
+	Action string `xml:"http://www.w3.org/2007/05/addressing/metadata Action,attr"`
+}
+
+// see https://www.w3.org/TR/wsdl20-adjuncts/#soap-binding
+type wsdl2Binding struct {
+	Name       string                   `xml:"name,attr"`
+	Interface  string                   `xml:"interface,attr"`
+	Type       string                   `xml:"type,attr"`
+	Protocol   string                   `xml:"http://www.w3.org/ns/wsdl/soap protocol,attr"`
+	Version    string                   `xml:"http://www.w3.org/ns/wsdl/soap version,attr"`
+	Operations []*wsdl2BindingOperation `xml:"http://www.w3.org/ns/wsdl operation"`
+}
+
+type wsdl2BindingOperation struct {
+	Ref     string                 `xml:"ref,attr"`
+	Action  string                 `xml:"http://www.w3.org/ns/wsdl/soap action,attr"`
+	Inputs  []*wsdl2BindingMessage `xml:"http://www.w3.org/ns/wsdl input"`
+	Outputs []*wsdl2BindingMessage `xml:"http://www.w3.org/ns/wsdl output"`
+}
+
+type wsdl2BindingMessage struct {
+	Headers []*wsdl2SoapHeader `xml:"http://www.w3.org/ns/wsdl/soap header"`
+}
+
+// see https://www.w3.org/TR/wsdl20-adjuncts/#soap-header-block-decl
+type wsdl2SoapHeader struct {
+	Element  string `xml:"element,attr"`
+	Required bool   `xml:"required,attr"`
+}
+
+type wsdl2Service struct {
+	Name      string           `xml:"name,attr"`
+	Interface string           `xml:"interface,attr"`
+	Endpoints []*wsdl2Endpoint `xml:"http://www.w3.org/ns/wsdl endpoint"`
+}
+
+type wsdl2Endpoint struct {
+	Name    string `xml:"name,attr"`
+	Binding string `xml:"binding,attr"`
+	Address string `xml:"address,attr"`
+}
+
+// decodeDescription parses a WSDL 2.0 document into definitions with resolved names
+func decodeDescription(content []byte) (*wsdlDefinitions, error) {
+	var desc *wsdl2Description
+	if err := decodeDocument(content, &desc); err != nil {
+		return nil, err
+	}
+	return desc.definitions()
+}
+
+func (desc *wsdl2Description) resolveQName(qname string) xml.Name {
+	return namespaces(desc.Attrs).resolve(qname)
+}
+
+// interfaceOperations returns the operations of an interface and the interfaces it extends
+func (desc *wsdl2Description) interfaceOperations(iface *wsdl2Interface, visited map[string]bool) []*wsdl2InterfaceOperation {
+	if visited[iface.Name] {
+		return nil
+	}
+	visited[iface.Name] = true
+	operations := iface.Operations
+	for _, name := range strings.Fields(iface.Extends) {
+		if extended := desc.iface(desc.resolveQName(name)); extended != nil {
+			operations = append(operations, desc.interfaceOperations(extended, visited)...)
+		}
+	}
+	return operations
+}
+
+// interfaceFault looks up a fault declared by an interface or the interfaces it extends
+func (desc *wsdl2Description) interfaceFault(iface *wsdl2Interface, name xml.Name, visited map[string]bool) *wsdl2InterfaceFault {
+	if visited[iface.Name] {
+		return nil
+	}
+	visited[iface.Name] = true
+	for _, f := range iface.Faults {
+		if f.Name == name.Local {
+			return f
+		}
+	}
+	for _, extends := range strings.Fields(iface.Extends) {
+		if extended := desc.iface(desc.resolveQName(extends)); extended != nil {
+			if f := desc.interfaceFault(extended, name, visited); f != nil {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+func (desc *wsdl2Description) iface(name xml.Name) *wsdl2Interface {
+	if name.Space != desc.TargetNamespace {
+		return nil
+	}
+	for _, i := range desc.Interfaces {
+		if i.Name == name.Local {
+			return i
+		}
+	}
+	return nil
+}
+
+// definitions maps the description onto the WSDL 1.1 model.
+// Interfaces can only extend interfaces declared in the same document.
+func (desc *wsdl2Description) definitions() (*wsdlDefinitions, error) {
+	d := &wsdlDefinitions{
+		TargetNamespace: desc.TargetNamespace,
+		Imports:         append(append([]*wsdlImport{}, desc.Imports...), desc.Includes...),
+		Types:           desc.Types,
+		Attrs:           desc.Attrs,
+	}
+	qname := func(local string) xml.Name {
+		return xml.Name{Space: desc.TargetNamespace, Local: local}
+	}
+	// message adds a message with a part for the element and returns its name, faults share a message
+	messages := map[string]bool{}
+	message := func(name, element string) xml.Name {
+		if messages[name] {
+			return qname(name)
+		}
+		messages[name] = true
+		m := &wsdlMessage{Name: name, QName: qname(name)}
+		if element != "" && element[0] != '#' {
+			m.Parts = append(m.Parts, &wsdlMessagePart{Name: wsdl2Part, Element: element, ElementName: desc.resolveQName(element)})
+		}
+		d.Messages = append(d.Messages, m)
+		return m.QName
+	}
+
+	for _, iface := range desc.Interfaces {
+		pt := &wsdlPortTypes{Name: iface.Name, QName: qname(iface.Name)}
+		for _, o := range desc.interfaceOperations(iface, map[string]bool{}) {
+			op := &wsdlOperation{Name: o.Name}
+			for _, in := range o.Inputs {
+				name := message(iface.Name+"."+o.Name+".In", in.Element)
+				op.Inputs = append(op.Inputs, &wsdlOperationInput{Message: name.Local, MessageName: name, WsawAction: in.Action})
+			}
+			for _, out := range o.Outputs {
+				name := message(iface.Name+"."+o.Name+".Out", out.Element)
+				op.Outputs = append(op.Outputs, &wsdlOperationOutput{Message: name.Local, MessageName: name, WsawAction: out.Action})
+			}
+			for _, f := range o.OutFaults {
+				ref := desc.resolveQName(f.Ref)
+				fault := desc.interfaceFault(iface, ref, map[string]bool{})
+				if fault == nil {
+					return nil, fmt.Errorf("could not find fault %q of operation %q in interface %q", f.Ref, o.Name, iface.Name)
+				}
+				name := message(iface.Name+"."+fault.Name, fault.Element)
+				op.Faults = append(op.Faults, &wsdlOperationFault{Name: fault.Name, Message: name.Local, MessageName: name, WsawAction: f.Action})
+			}
+			pt.Operations = append(pt.Operations, op)
+		}
+		d.PortTypes = append(d.PortTypes, pt)
+	}
+
+	soapBindings := map[string]bool{}
+	// unsupported are the reasons the client can't use SOAP bindings
+	unsupported := map[string]string{}
+	for _, b := range desc.Bindings {
+		if b.Type != wsoapNS {
+			// HTTP bindings can't be used by the client
+			continue
+		}
+		if version := b.version(); version != "1.1" {
+			// the client only sends SOAP 1.1 envelopes
+			reason := fmt.Sprintf("binding %q uses SOAP %s", b.Name, version)
+			if b.Version == "" {
+				reason = fmt.Sprintf("binding %q has no wsoap:version and defaults to SOAP %s", b.Name, version)
+			}
+			unsupported[b.Name] = reason + `, only bindings with wsoap:version="1.1" are supported`
+			continue
+		}
+		soapBindings[b.Name] = true
+		binding := &wsdlBinding{
+			Name:         b.Name,
+			Type:         b.Interface,
+			QName:        qname(b.Name),
+			TypeName:     desc.resolveQName(b.Interface),
+			SoapBindings: []*soapBinding{{Transport: b.Protocol, Style: "document"}},
+		}
+		// every operation of the interface is bound, binding operations only add SOAP properties.
+		// Only the explicitly bound operations are known if the interface is declared in another document.
+		operations := make([]*wsdl2InterfaceOperation, 0, len(b.Operations))
+		iface := desc.iface(binding.TypeName)
+		if iface != nil {
+			operations = desc.interfaceOperations(iface, map[string]bool{})
+		} else {
+			for _, bo := range b.Operations {
+				ref := desc.resolveQName(bo.Ref)
+				operations = append(operations, &wsdl2InterfaceOperation{
+					Name:    ref.Local,
+					Inputs:  []*wsdl2MessageRef{{}},
+					Outputs: []*wsdl2MessageRef{{}},
+				})
+			}
+		}
+		for _, o := range operations {
+			op := &wsdlOperation{Name: o.Name, SoapOperations: []*soapOperation{{}}}
+			in := &wsdlOperationInput{SoapBodies: []*soapBody{{Use: "literal"}}}
+			out := &wsdlOperationOutput{SoapBodies: []*soapBody{{Use: "literal"}}}
+			for _, bo := range b.Operations {
+				if desc.resolveQName(bo.Ref).Local != o.Name {
+					continue
+				}
+				op.SoapOperations[0].SoapAction = bo.Action
+				if iface == nil {
+					continue
+				}
+				if len(bo.Inputs) > 0 && len(o.Inputs) > 0 {
+					in.SoapHeaders = desc.headers(d, qname(iface.Name+"."+o.Name+".In"), bo.Inputs[0].Headers)
+				}
+				if len(bo.Outputs) > 0 && len(o.Outputs) > 0 {
+					out.SoapHeaders = desc.headers(d, qname(iface.Name+"."+o.Name+".Out"), bo.Outputs[0].Headers)
+				}
+			}
+			if len(in.SoapHeaders) > 0 {
+				in.SoapBodies[0].Parts = wsdl2Part
+			}
+			if len(out.SoapHeaders) > 0 {
+				out.SoapBodies[0].Parts = wsdl2Part
+			}
+			if len(o.Inputs) > 0 {
+				op.Inputs = append(op.Inputs, in)
+			}
+			if len(o.Outputs) > 0 {
+				op.Outputs = append(op.Outputs, out)
+			}
+			binding.Operations = append(binding.Operations, op)
+		}
+		d.Bindings = append(d.Bindings, binding)
+	}
+
+	for _, s := range desc.Services {
+		service := &wsdlService{Name: s.Name}
+		for _, e := range s.Endpoints {
+			port := &wsdlPort{Name: e.Name, Binding: e.Binding, BindingName: desc.resolveQName(e.Binding)}
+			// endpoints of bindings declared in other documents are assumed to be SOAP endpoints
+			if e.Address != "" && (port.BindingName.Space != desc.TargetNamespace || soapBindings[port.BindingName.Local]) {
+				port.SoapAddresses = []*soapAddress{{Location: e.Address}}
+			} else if port.BindingName.Space == desc.TargetNamespace {
+				port.Unsupported = unsupported[port.BindingName.Local]
+			}
+			service.Ports = append(service.Ports, port)
+		}
+		d.Services = append(d.Services, service)
+	}
+	return d, nil
+}
+
+// version returns the SOAP version of a SOAP binding
+func (b *wsdl2Binding) version() string {
+	if b.Version == "" {
+		return wsoapVersion
+	}
+	return b.Version
+}
+
+// headers adds a part for every required wsoap:header to the message and returns the matching soap:header declarations.
+// Optional header blocks are not declared because their element isn't part of the message.
+func (desc *wsdl2Description) headers(d *wsdlDefinitions, message xml.Name, headers []*wsdl2SoapHeader) []*soapHeader {
+	var m *wsdlMessage
+	for _, candidate := range d.Messages {
+		if candidate.QName == message {
+			m = candidate
+		}
+	}
+	var declared []*soapHeader
+	for _, h := range headers {
+		if !h.Required || m == nil {
+			continue
+		}
+		element := desc.resolveQName(h.Element)
+		if !m.hasPart(element.Local) {
+			// the message is shared by all bindings of the interface
+			m.Parts = append(m.Parts, &wsdlMessagePart{Name: element.Local, Element: h.Element, ElementName: element})
+		}
+		declared = append(declared, &soapHeader{Message: message.Local, MessageName: message, Part: element.Local, Use: "literal"})
+	}
+	return declared
+}
+
+func (m *wsdlMessage) hasPart(name string) bool {
+	for _, p := range m.Parts {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
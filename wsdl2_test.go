@@ -0,0 +1,99 @@
+package gosoap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func quote20Name(local string) xml.Name {
+	return xml.Name{Space: "http://example.com/quote20", Local: local}
+}
+
+func TestWSDL20(t *testing.T) {
+	t.Parallel()
+	spec, err := os.ReadFile("testdata/quote20.wsdl")
+	require.NoError(t, err)
+	d, err := ParseWSDL(SourceFromBytes(spec))
+	require.NoError(t, err)
+
+	service := d.Service("QuoteService")
+	require.NotNil(t, service)
+	require.Len(t, service.Ports, 2)
+	port := service.Port("QuoteSoapEndpoint")
+	assert.Equal(t, "http://example.com/quote20/soap", port.Address)
+	assert.Empty(t, service.Port("QuoteHTTPEndpoint").Address, "HTTP bindings are not mapped")
+	assert.Nil(t, d.Binding(quote20Name("QuoteHTTPBinding")))
+
+	binding := port.Binding
+	require.NotNil(t, binding)
+	assert.Equal(t, quote20Name("QuoteInterface"), binding.PortType)
+	assert.Equal(t, "document", binding.Style)
+	assert.Equal(t, "http://www.w3.org/2006/01/soap11/bindings/HTTP/", binding.Transport)
+
+	var operations []string
+	for _, o := range binding.Operations {
+		operations = append(operations, o.Name)
+	}
+	assert.Equal(t, []string{"GetQuote", "Ping"}, operations, "operations of extended interfaces are inherited")
+
+	op := binding.Operation("GetQuote")
+	assert.Equal(t, "http://example.com/quote20/GetQuote", op.SOAPAction)
+	require.NotNil(t, op.Input)
+	assert.Equal(t, "http://example.com/quote20/GetQuoteRequest", op.Input.Action)
+	assert.Equal(t, quote20Name("GetQuote"), op.Input.Message.Part("parameters").Element)
+	assert.Equal(t, &BodyBinding{Parts: []string{"parameters"}, Use: "literal"}, op.Input.Body)
+	require.Len(t, op.Input.Headers, 1, "optional header blocks are not declared")
+	assert.Equal(t, quote20Name("Auth"), op.Input.Message.Part(op.Input.Headers[0].Part).Element)
+	assert.Equal(t, quote20Name("GetQuoteResponse"), op.Output.Message.Part("parameters").Element)
+	require.Len(t, op.Faults, 1)
+	assert.Equal(t, "UnknownSymbol", op.Faults[0].Name)
+	assert.Equal(t, quote20Name("UnknownSymbol"), op.Faults[0].Message.Parts[0].Element)
+
+	ping := binding.Operation("Ping")
+	assert.Empty(t, ping.SOAPAction)
+	assert.Nil(t, ping.Output)
+}
+
+func TestWSDL20Client(t *testing.T) {
+	t.Parallel()
+	server, reqBody := newCaptureServer(t)
+	client := newTestClient(t, "./testdata/quote20.wsdl", server, Config{})
+
+	_, err := client.Call(context.Background(), "GetQuote", Params{"Symbol": "ACME"})
+	assert.EqualError(t, err, `operation "GetQuote" requires the SOAP header "Auth" which is missing from the request`)
+
+	auth := &HeaderEntry{Name: "Auth", Namespace: "http://example.com/quote20", Content: "secret"}
+	_, err = client.Call(context.Background(), "GetQuote", Params{"Symbol": "ACME"}, auth)
+	require.NoError(t, err)
+	assert.Contains(t, string(*reqBody), `<Auth xmlns="http://example.com/quote20">secret</Auth>`)
+	assert.Contains(t, string(*reqBody), `<GetQuote xmlns="http://example.com/quote20">`)
+	assert.Contains(t, string(*reqBody), `<Symbol>ACME</Symbol>`)
+}
+
+func TestWSDL20SOAP12(t *testing.T) {
+	t.Parallel()
+	spec, err := os.ReadFile("testdata/quote20soap12.wsdl")
+	require.NoError(t, err)
+	d, err := ParseWSDL(SourceFromBytes(spec))
+	require.NoError(t, err)
+	assert.Nil(t, d.Binding(quote20Name("QuoteSoapBinding")), "bindings without a version default to SOAP 1.2")
+	assert.Empty(t, d.Service("QuoteService").Port("QuoteSoapEndpoint").Address)
+
+	_, err = NewClient(SourceFromBytes(spec), nil)
+	assert.EqualError(t, err, `could not determin SOAP address: WSDL port "QuoteSoapEndpoint" can't be used: binding "QuoteSoapBinding" has no wsoap:version and defaults to SOAP 1.2, only bindings with wsoap:version="1.1" are supported`)
+
+	explicit := bytes.Replace(spec, []byte(`<binding name="QuoteSoapBinding"`), []byte(`<binding wsoap:version="1.2" name="QuoteSoapBinding"`), 1)
+	_, err = NewClient(SourceFromBytes(explicit), nil)
+	assert.EqualError(t, err, `could not determin SOAP address: WSDL port "QuoteSoapEndpoint" can't be used: binding "QuoteSoapBinding" uses SOAP 1.2, only bindings with wsoap:version="1.1" are supported`)
+
+	soap11 := bytes.Replace(spec, []byte(`<binding name="QuoteSoapBinding"`), []byte(`<binding wsoap:version="1.1" name="QuoteSoapBinding"`), 1)
+	client, err := NewClient(SourceFromBytes(soap11), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/quote20/soap", client.Definitions().Service("QuoteService").Port("QuoteSoapEndpoint").Address)
+}
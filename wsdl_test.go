@@ -1,11 +1,17 @@
 package gosoap
 
 import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_getWsdlBody(t *testing.T) {
@@ -55,7 +61,7 @@ func Test_getWsdlBody(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			_, err := getWsdlBody(tt.args.u, nil)
+			_, err := getWsdlBody(context.Background(), tt.args.u, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getwsdlBody() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -89,3 +95,43 @@ func TestFaultString(t *testing.T) {
 		assert.Equal(t, testCase.expectedFaultStr, faultStr)
 	}
 }
+
+func TestOverloadedOperations(t *testing.T) {
+	t.Parallel()
+	var action string
+	var reqBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		action = r.Header.Get("SOAPAction")
+		reqBody, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body/></soap:Envelope>`))
+	}))
+	t.Cleanup(server.Close)
+	client := newTestClient(t, "./testdata/overload.wsdl", server, Config{})
+
+	_, err := client.Call(context.Background(), "Lookup", Params{"Id": 1})
+	assert.EqualError(t, err, `operation "Lookup" is ambiguous, set the input or output name of the request to select one of: `+
+		`input "ById" output "ByIdResult", input "ByName" output "ByNameResult"`)
+	_, err = client.state().binding.GetSoapActionFromWsdlOperation("Lookup")
+	assert.ErrorContains(t, err, "is ambiguous")
+
+	_, err = client.Do(context.Background(), &Request{WSDLOperation: "Lookup", InputName: "ByName", Body: Params{"Name": "ACME"}})
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/overload/LookupByName", action)
+	assert.Contains(t, string(reqBody), `<LookupByName xmlns="http://example.com/overload">`)
+
+	_, err = client.Do(context.Background(), &Request{WSDLOperation: "Lookup", OutputName: "ByIdResult", Body: Params{"Id": 1}})
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/overload/LookupById", action)
+	assert.Contains(t, string(reqBody), `<LookupById xmlns="http://example.com/overload">`)
+
+	_, err = client.Do(context.Background(), &Request{WSDLOperation: "Lookup", InputName: "ByEmail"})
+	assert.EqualError(t, err, `could not find operating matching "Lookup" in binding "DirectoryBinding"`)
+
+	d := client.Definitions()
+	operations := d.Binding(xml.Name{Space: "http://example.com/overload", Local: "DirectoryBinding"}).Operations
+	require.Len(t, operations, 2)
+	assert.Equal(t, "ByName", operations[1].Input.Name)
+	assert.Equal(t, "ByNameResult", operations[1].Output.Name)
+	assert.Equal(t, xml.Name{Space: "http://example.com/overload", Local: "LookupByNameIn"}, operations[1].Input.Message.Name)
+}
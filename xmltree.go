@@ -0,0 +1,223 @@
+package gosoap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+const xmlNS = "http://www.w3.org/XML/1998/namespace"
+
+// xmlNode is a generic XML tree used where the document has to be inspected or rewritten
+// before it can be decoded into a value, names are namespace resolved.
+// Character data is stored in nodes without a name.
+type xmlNode struct {
+	Name     xml.Name
+	Attr     []xml.Attr
+	Children []*xmlNode
+	Text     string
+}
+
+func (n *xmlNode) isText() bool {
+	return n.Name.Local == ""
+}
+
+func (n *xmlNode) attr(space, local string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// elements returns the child elements of a node, skipping character data
+func (n *xmlNode) elements() []*xmlNode {
+	var elements []*xmlNode
+	for _, c := range n.Children {
+		if !c.isText() {
+			elements = append(elements, c)
+		}
+	}
+	return elements
+}
+
+// text returns the concatenated character data of the direct children
+func (n *xmlNode) text() string {
+	var b strings.Builder
+	for _, c := range n.Children {
+		if c.isText() {
+			b.WriteString(c.Text)
+		}
+	}
+	return b.String()
+}
+
+func (n *xmlNode) clone() *xmlNode {
+	c := &xmlNode{Name: n.Name, Text: n.Text}
+	c.Attr = append(c.Attr, n.Attr...)
+	for _, child := range n.Children {
+		c.Children = append(c.Children, child.clone())
+	}
+	return c
+}
+
+// parseXMLNodes parses a document or a fragment with multiple root elements
+func parseXMLNodes(data []byte) ([]*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	var roots []*xmlNode
+	var stack []*xmlNode
+	for {
+		t, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			n := &xmlNode{Name: t.Name, Attr: t.Copy().Attr}
+			if len(stack) == 0 {
+				roots = append(roots, n)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, n)
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, &xmlNode{Text: string(t)})
+			}
+		}
+	}
+	return roots, nil
+}
+
+// parseXMLFragment parses content cut out of a document, like the inner XML of the SOAP body.
+// inherited are the namespace declarations in scope where the fragment was cut out, including the default namespace.
+func parseXMLFragment(data []byte, inherited namespaces) ([]*xmlNode, error) {
+	if len(inherited) == 0 {
+		return parseXMLNodes(data)
+	}
+	var b bytes.Buffer
+	// later declarations override earlier ones, like those of the body element override the envelope's
+	declared := map[string]string{}
+	var prefixes []string
+	for _, a := range inherited {
+		var name string
+		switch {
+		case a.Name.Space == "xmlns":
+			name = "xmlns:" + a.Name.Local
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			name = "xmlns"
+		default:
+			continue
+		}
+		if _, ok := declared[name]; !ok {
+			prefixes = append(prefixes, name)
+		}
+		declared[name] = a.Value
+	}
+	b.WriteString("<fragment")
+	for _, name := range prefixes {
+		b.WriteString(" " + attrString(name, declared[name]))
+	}
+	b.WriteString(">")
+	b.Write(data)
+	b.WriteString("</fragment>")
+	roots, err := parseXMLNodes(b.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return roots[0].elements(), nil
+}
+
+// marshalXMLNodes serializes nodes, declaring the namespaces the names were resolved to.
+// Prefixed namespace declarations of the original document are kept so QName values stay resolvable.
+func marshalXMLNodes(nodes []*xmlNode) ([]byte, error) {
+	w := &nodeWriter{}
+	for _, n := range nodes {
+		if err := w.write(n, "", nil); err != nil {
+			return nil, err
+		}
+	}
+	return w.buf.Bytes(), nil
+}
+
+type nodeWriter struct {
+	buf     bytes.Buffer
+	counter int
+}
+
+// write serializes a node, defaultNS is the default namespace in scope and prefixes maps namespaces to declared prefixes
+func (w *nodeWriter) write(n *xmlNode, defaultNS string, prefixes map[string]string) error {
+	if n.isText() {
+		return xml.EscapeText(&w.buf, []byte(n.Text))
+	}
+
+	scope := make(map[string]string, len(prefixes))
+	for ns, prefix := range prefixes {
+		scope[ns] = prefix
+	}
+	var attrs []string
+	for _, a := range n.Attr {
+		if a.Name.Space == "xmlns" {
+			scope[a.Value] = a.Name.Local
+			attrs = append(attrs, attrString("xmlns:"+a.Name.Local, a.Value))
+		}
+	}
+	if n.Name.Space != defaultNS {
+		attrs = append(attrs, attrString("xmlns", n.Name.Space))
+	}
+	for _, a := range n.Attr {
+		switch {
+		case a.Name.Space == "xmlns", a.Name.Space == "" && a.Name.Local == "xmlns":
+			continue
+		case a.Name.Space == "":
+			attrs = append(attrs, attrString(a.Name.Local, a.Value))
+		case a.Name.Space == xmlNS:
+			attrs = append(attrs, attrString("xml:"+a.Name.Local, a.Value))
+		default:
+			prefix, ok := scope[a.Name.Space]
+			if !ok {
+				w.counter++
+				prefix = fmt.Sprintf("_%d", w.counter)
+				scope[a.Name.Space] = prefix
+				attrs = append(attrs, attrString("xmlns:"+prefix, a.Name.Space))
+			}
+			attrs = append(attrs, attrString(prefix+":"+a.Name.Local, a.Value))
+		}
+	}
+
+	w.buf.WriteString("<" + n.Name.Local)
+	for _, a := range attrs {
+		w.buf.WriteString(" " + a)
+	}
+	w.buf.WriteString(">")
+	for _, c := range n.Children {
+		if err := w.write(c, n.Name.Space, scope); err != nil {
+			return err
+		}
+	}
+	w.buf.WriteString("</" + n.Name.Local + ">")
+	return nil
+}
+
+func attrString(name, value string) string {
+	var b strings.Builder
+	b.WriteString(name + `="`)
+	_ = xml.EscapeText(&b, []byte(value))
+	b.WriteString(`"`)
+	return b.String()
+}
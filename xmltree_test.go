@@ -0,0 +1,34 @@
+package gosoap
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseXMLFragment(t *testing.T) {
+	t.Parallel()
+	inherited := namespaces{
+		{Name: xml.Name{Space: "xmlns", Local: "soap"}, Value: soap11EnvelopeNS},
+		{Name: xml.Name{Local: "xmlns"}, Value: "urn:envelope"},
+		{Name: xml.Name{Space: "xmlns", Local: "o"}, Value: "urn:envelope"},
+		{Name: xml.Name{Local: "xmlns"}, Value: "urn:order"},
+		{Name: xml.Name{Space: "xmlns", Local: "o"}, Value: "urn:order"},
+	}
+	nodes, err := parseXMLFragment([]byte(`<GetOrderResponse><OrderID>1</OrderID><o:Status>new</o:Status><Note xmlns="">x</Note></GetOrderResponse>`), inherited)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, xml.Name{Space: "urn:order", Local: "GetOrderResponse"}, nodes[0].Name)
+
+	var names []xml.Name
+	for _, c := range nodes[0].elements() {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []xml.Name{
+		{Space: "urn:order", Local: "OrderID"},
+		{Space: "urn:order", Local: "Status"},
+		{Local: "Note"},
+	}, names)
+}
@@ -0,0 +1,739 @@
+package gosoap
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// The XSD 1.0 component model, see https://www.w3.org/TR/xmlschema-1/
+//
+// QName valued attributes are kept as written and resolved into the *Name fields when the
+// schemas of a WSDL are indexed by newSchemaSet.
+
+type xsdSchema struct {
+	TargetNamespace      string               `xml:"targetNamespace,attr"`
+	ElementFormDefault   string               `xml:"elementFormDefault,attr"`
+	AttributeFormDefault string               `xml:"attributeFormDefault,attr"`
+	Imports              []*xsdImport         `xml:"http://www.w3.org/2001/XMLSchema import"`
+	Includes             []*xsdInclude        `xml:"http://www.w3.org/2001/XMLSchema include"`
+	Elements             []*xsdElement        `xml:"http://www.w3.org/2001/XMLSchema element"`
+	ComplexTypes         []*xsdComplexType    `xml:"http://www.w3.org/2001/XMLSchema complexType"`
+	SimpleTypes          []*xsdSimpleType     `xml:"http://www.w3.org/2001/XMLSchema simpleType"`
+	Groups               []*xsdGroup          `xml:"http://www.w3.org/2001/XMLSchema group"`
+	AttributeGroups      []*xsdAttributeGroup `xml:"http://www.w3.org/2001/XMLSchema attributeGroup"`
+	Attributes           []*xsdAttribute      `xml:"http://www.w3.org/2001/XMLSchema attribute"`
+	Attrs                []xml.Attr           `xml:",any,attr"`
+
+	// Namespaces in scope of the schema element, including the declarations of an enclosing WSDL
+	Namespaces namespaces `xml:"-"`
+	// Chameleon is set for an included schema without target namespace that took the namespace of the including schema
+	Chameleon bool `xml:"-"`
+}
+
+type xsdImport struct {
+	SchemaLocation string `xml:"schemaLocation,attr"`
+	Namespace      string `xml:"namespace,attr"`
+}
+
+type xsdInclude struct {
+	SchemaLocation string `xml:"schemaLocation,attr"`
+}
+
+type xsdElement struct {
+	Name              string          `xml:"name,attr"`
+	Ref               string          `xml:"ref,attr"`
+	Nillable          bool            `xml:"nillable,attr"`
+	Abstract          bool            `xml:"abstract,attr"`
+	Type              string          `xml:"type,attr"`
+	SubstitutionGroup string          `xml:"substitutionGroup,attr"`
+	MinOccurs         string          `xml:"minOccurs,attr"`
+	MaxOccurs         string          `xml:"maxOccurs,attr"`
+	Default           string          `xml:"default,attr"`
+	Fixed             string          `xml:"fixed,attr"`
+	Form              string          `xml:"form,attr"`
+	ComplexType       *xsdComplexType `xml:"http://www.w3.org/2001/XMLSchema complexType"`
+	SimpleType        *xsdSimpleType  `xml:"http://www.w3.org/2001/XMLSchema simpleType"`
+
+	// QName is the name the element appears with in documents
+	QName                 xml.Name `xml:"-"`
+	RefName               xml.Name `xml:"-"`
+	TypeName              xml.Name `xml:"-"`
+	SubstitutionGroupName xml.Name `xml:"-"`
+}
+
+type xsdComplexType struct {
+	Name            string               `xml:"name,attr"`
+	Mixed           bool                 `xml:"mixed,attr"`
+	Abstract        bool                 `xml:"abstract,attr"`
+	Sequence        *xsdModelGroup       `xml:"http://www.w3.org/2001/XMLSchema sequence"`
+	Choice          *xsdModelGroup       `xml:"http://www.w3.org/2001/XMLSchema choice"`
+	All             *xsdModelGroup       `xml:"http://www.w3.org/2001/XMLSchema all"`
+	Group           *xsdGroup            `xml:"http://www.w3.org/2001/XMLSchema group"`
+	ComplexContent  *xsdComplexContent   `xml:"http://www.w3.org/2001/XMLSchema complexContent"`
+	SimpleContent   *xsdSimpleContent    `xml:"http://www.w3.org/2001/XMLSchema simpleContent"`
+	Attributes      []*xsdAttribute      `xml:"http://www.w3.org/2001/XMLSchema attribute"`
+	AttributeGroups []*xsdAttributeGroup `xml:"http://www.w3.org/2001/XMLSchema attributeGroup"`
+	AnyAttribute    *xsdAnyAttribute     `xml:"http://www.w3.org/2001/XMLSchema anyAttribute"`
+
+	QName xml.Name `xml:"-"`
+}
+
+// modelGroup returns the top level model group of the content of a type, it is nil for empty and simple content
+func (ct *xsdComplexType) modelGroup() *xsdModelGroup {
+	switch {
+	case ct.Sequence != nil:
+		return ct.Sequence
+	case ct.Choice != nil:
+		return ct.Choice
+	case ct.All != nil:
+		return ct.All
+	case ct.Group != nil:
+		return &xsdModelGroup{Kind: "sequence", Particles: []*xsdParticle{{Group: ct.Group}}}
+	}
+	return nil
+}
+
+// derivation returns the extension or restriction of a type with complex or simple content
+func (ct *xsdComplexType) derivation() (d *xsdDerivation, extension bool) {
+	var ext, res *xsdDerivation
+	switch {
+	case ct.ComplexContent != nil:
+		ext, res = ct.ComplexContent.Extension, ct.ComplexContent.Restriction
+	case ct.SimpleContent != nil:
+		ext, res = ct.SimpleContent.Extension, ct.SimpleContent.Restriction
+	}
+	if ext != nil {
+		return ext, true
+	}
+	return res, false
+}
+
+type xsdComplexContent struct {
+	Mixed       bool           `xml:"mixed,attr"`
+	Extension   *xsdDerivation `xml:"http://www.w3.org/2001/XMLSchema extension"`
+	Restriction *xsdDerivation `xml:"http://www.w3.org/2001/XMLSchema restriction"`
+}
+
+type xsdSimpleContent struct {
+	Extension   *xsdDerivation `xml:"http://www.w3.org/2001/XMLSchema extension"`
+	Restriction *xsdDerivation `xml:"http://www.w3.org/2001/XMLSchema restriction"`
+}
+
+// xsdDerivation is an extension or restriction of complex or simple content
+type xsdDerivation struct {
+	Base            string               `xml:"base,attr"`
+	Sequence        *xsdModelGroup       `xml:"http://www.w3.org/2001/XMLSchema sequence"`
+	Choice          *xsdModelGroup       `xml:"http://www.w3.org/2001/XMLSchema choice"`
+	All             *xsdModelGroup       `xml:"http://www.w3.org/2001/XMLSchema all"`
+	Group           *xsdGroup            `xml:"http://www.w3.org/2001/XMLSchema group"`
+	Attributes      []*xsdAttribute      `xml:"http://www.w3.org/2001/XMLSchema attribute"`
+	AttributeGroups []*xsdAttributeGroup `xml:"http://www.w3.org/2001/XMLSchema attributeGroup"`
+	AnyAttribute    *xsdAnyAttribute     `xml:"http://www.w3.org/2001/XMLSchema anyAttribute"`
+	// facets of a simple content restriction
+	Facets xsdFacets `xml:",any"`
+
+	BaseName xml.Name `xml:"-"`
+}
+
+func (d *xsdDerivation) modelGroup() *xsdModelGroup {
+	ct := xsdComplexType{Sequence: d.Sequence, Choice: d.Choice, All: d.All, Group: d.Group}
+	return ct.modelGroup()
+}
+
+// xsdModelGroup is a sequence, choice or all, the order of its particles is kept
+type xsdModelGroup struct {
+	Kind      string
+	MinOccurs string
+	MaxOccurs string
+	Particles []*xsdParticle
+}
+
+// xsdParticle holds exactly one of its fields
+type xsdParticle struct {
+	Element    *xsdElement
+	ModelGroup *xsdModelGroup
+	Group      *xsdGroup
+	Any        *xsdAny
+}
+
+func (g *xsdModelGroup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	g.Kind = start.Name.Local
+	for _, a := range start.Attr {
+		switch {
+		case a.Name.Space != "":
+		case a.Name.Local == "minOccurs":
+			g.MinOccurs = a.Value
+		case a.Name.Local == "maxOccurs":
+			g.MaxOccurs = a.Value
+		}
+	}
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			var p xsdParticle
+			var v any
+			switch {
+			case t.Name.Space != xsdNS:
+			case t.Name.Local == "element":
+				p.Element = &xsdElement{}
+				v = p.Element
+			case t.Name.Local == "sequence", t.Name.Local == "choice", t.Name.Local == "all":
+				p.ModelGroup = &xsdModelGroup{}
+				v = p.ModelGroup
+			case t.Name.Local == "group":
+				p.Group = &xsdGroup{}
+				v = p.Group
+			case t.Name.Local == "any":
+				p.Any = &xsdAny{}
+				v = p.Any
+			}
+			if v == nil {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.DecodeElement(v, &t); err != nil {
+				return err
+			}
+			g.Particles = append(g.Particles, &p)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// xsdGroup is a named model group definition or a reference to one
+type xsdGroup struct {
+	Name      string         `xml:"name,attr"`
+	Ref       string         `xml:"ref,attr"`
+	MinOccurs string         `xml:"minOccurs,attr"`
+	MaxOccurs string         `xml:"maxOccurs,attr"`
+	Sequence  *xsdModelGroup `xml:"http://www.w3.org/2001/XMLSchema sequence"`
+	Choice    *xsdModelGroup `xml:"http://www.w3.org/2001/XMLSchema choice"`
+	All       *xsdModelGroup `xml:"http://www.w3.org/2001/XMLSchema all"`
+
+	QName   xml.Name `xml:"-"`
+	RefName xml.Name `xml:"-"`
+}
+
+func (g *xsdGroup) modelGroup() *xsdModelGroup {
+	switch {
+	case g.Sequence != nil:
+		return g.Sequence
+	case g.Choice != nil:
+		return g.Choice
+	}
+	return g.All
+}
+
+type xsdAny struct {
+	Namespace       string `xml:"namespace,attr"`
+	ProcessContents string `xml:"processContents,attr"`
+	MinOccurs       string `xml:"minOccurs,attr"`
+	MaxOccurs       string `xml:"maxOccurs,attr"`
+}
+
+type xsdAttribute struct {
+	Name       string         `xml:"name,attr"`
+	Ref        string         `xml:"ref,attr"`
+	Type       string         `xml:"type,attr"`
+	Use        string         `xml:"use,attr"`
+	Default    string         `xml:"default,attr"`
+	Fixed      string         `xml:"fixed,attr"`
+	Form       string         `xml:"form,attr"`
+	SimpleType *xsdSimpleType `xml:"http://www.w3.org/2001/XMLSchema simpleType"`
+
+	QName    xml.Name `xml:"-"`
+	RefName  xml.Name `xml:"-"`
+	TypeName xml.Name `xml:"-"`
+}
+
+// xsdAttributeGroup is a named attribute group definition or a reference to one
+type xsdAttributeGroup struct {
+	Name            string               `xml:"name,attr"`
+	Ref             string               `xml:"ref,attr"`
+	Attributes      []*xsdAttribute      `xml:"http://www.w3.org/2001/XMLSchema attribute"`
+	AttributeGroups []*xsdAttributeGroup `xml:"http://www.w3.org/2001/XMLSchema attributeGroup"`
+	AnyAttribute    *xsdAnyAttribute     `xml:"http://www.w3.org/2001/XMLSchema anyAttribute"`
+
+	QName   xml.Name `xml:"-"`
+	RefName xml.Name `xml:"-"`
+}
+
+type xsdAnyAttribute struct {
+	Namespace       string `xml:"namespace,attr"`
+	ProcessContents string `xml:"processContents,attr"`
+}
+
+type xsdSimpleType struct {
+	Name        string          `xml:"name,attr"`
+	Restriction *xsdRestriction `xml:"http://www.w3.org/2001/XMLSchema restriction"`
+	List        *xsdList        `xml:"http://www.w3.org/2001/XMLSchema list"`
+	Union       *xsdUnion       `xml:"http://www.w3.org/2001/XMLSchema union"`
+
+	QName xml.Name `xml:"-"`
+}
+
+type xsdRestriction struct {
+	Base       string         `xml:"base,attr"`
+	SimpleType *xsdSimpleType `xml:"http://www.w3.org/2001/XMLSchema simpleType"`
+	Facets     xsdFacets      `xml:",any"`
+
+	BaseName xml.Name `xml:"-"`
+}
+
+// xsdFacets are the facets of a restriction in document order, other child elements like annotations are ignored
+// see https://www.w3.org/TR/xmlschema-2/#rf-facets
+type xsdFacets []*xsdFacet
+
+type xsdFacet struct {
+	XMLName xml.Name
+	Value   string `xml:"value,attr"`
+}
+
+// get returns the value of a facet that can appear once, like maxLength
+func (f xsdFacets) get(kind string) (string, bool) {
+	for _, facet := range f {
+		if facet.XMLName.Space == xsdNS && facet.XMLName.Local == kind {
+			return facet.Value, true
+		}
+	}
+	return "", false
+}
+
+// values returns the values of a facet that can appear multiple times, like enumeration and pattern
+func (f xsdFacets) values(kind string) []string {
+	var values []string
+	for _, facet := range f {
+		if facet.XMLName.Space == xsdNS && facet.XMLName.Local == kind {
+			values = append(values, facet.Value)
+		}
+	}
+	return values
+}
+
+type xsdList struct {
+	ItemType   string         `xml:"itemType,attr"`
+	SimpleType *xsdSimpleType `xml:"http://www.w3.org/2001/XMLSchema simpleType"`
+
+	ItemTypeName xml.Name `xml:"-"`
+}
+
+type xsdUnion struct {
+	MemberTypes string           `xml:"memberTypes,attr"`
+	SimpleTypes []*xsdSimpleType `xml:"http://www.w3.org/2001/XMLSchema simpleType"`
+
+	MemberTypeNames []xml.Name `xml:"-"`
+}
+
+// occurs parses a minOccurs or maxOccurs value, unbounded is returned as -1
+func occurs(value string) int {
+	if value == "" {
+		return 1
+	}
+	if value == "unbounded" {
+		return -1
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 1
+	}
+	return n
+}
+
+// xsdSchemaSet indexes the global components of all schemas of a WSDL by their qualified names
+type xsdSchemaSet struct {
+	schemas         []*xsdSchema
+	elements        map[xml.Name]*xsdElement
+	complexTypes    map[xml.Name]*xsdComplexType
+	simpleTypes     map[xml.Name]*xsdSimpleType
+	groups          map[xml.Name]*xsdGroup
+	attributeGroups map[xml.Name]*xsdAttributeGroup
+	attributes      map[xml.Name]*xsdAttribute
+	// substitutions maps the head of a substitution group to its members
+	substitutions map[xml.Name][]*xsdElement
+}
+
+// newSchemaSet resolves the QNames referenced by the components of the schemas and indexes them
+func newSchemaSet(schemas []*xsdSchema) *xsdSchemaSet {
+	set := &xsdSchemaSet{
+		schemas:         schemas,
+		elements:        map[xml.Name]*xsdElement{},
+		complexTypes:    map[xml.Name]*xsdComplexType{},
+		simpleTypes:     map[xml.Name]*xsdSimpleType{},
+		groups:          map[xml.Name]*xsdGroup{},
+		attributeGroups: map[xml.Name]*xsdAttributeGroup{},
+		attributes:      map[xml.Name]*xsdAttribute{},
+		substitutions:   map[xml.Name][]*xsdElement{},
+	}
+	for _, s := range schemas {
+		c := &schemaCompiler{schema: s}
+		global := func(local string) xml.Name {
+			return xml.Name{Space: s.TargetNamespace, Local: local}
+		}
+		for _, e := range s.Elements {
+			c.element(e, true)
+			set.elements[e.QName] = e
+			if e.SubstitutionGroupName.Local != "" {
+				set.substitutions[e.SubstitutionGroupName] = append(set.substitutions[e.SubstitutionGroupName], e)
+			}
+		}
+		for _, ct := range s.ComplexTypes {
+			ct.QName = global(ct.Name)
+			c.complexType(ct)
+			set.complexTypes[ct.QName] = ct
+		}
+		for _, st := range s.SimpleTypes {
+			st.QName = global(st.Name)
+			c.simpleType(st)
+			set.simpleTypes[st.QName] = st
+		}
+		for _, g := range s.Groups {
+			g.QName = global(g.Name)
+			c.group(g)
+			set.groups[g.QName] = g
+		}
+		for _, ag := range s.AttributeGroups {
+			ag.QName = global(ag.Name)
+			c.attributeGroup(ag)
+			set.attributeGroups[ag.QName] = ag
+		}
+		for _, a := range s.Attributes {
+			c.attribute(a, true)
+			set.attributes[a.QName] = a
+		}
+	}
+	return set
+}
+
+// schemaCompiler resolves the QNames of the components of one schema
+type schemaCompiler struct {
+	schema *xsdSchema
+}
+
+func (c *schemaCompiler) resolve(qname string) xml.Name {
+	if qname == "" {
+		return xml.Name{}
+	}
+	name := c.schema.Namespaces.resolve(qname)
+	if name.Space == "" && c.schema.Chameleon {
+		name.Space = c.schema.TargetNamespace
+	}
+	return name
+}
+
+func (c *schemaCompiler) element(e *xsdElement, global bool) {
+	e.RefName = c.resolve(e.Ref)
+	e.TypeName = c.resolve(e.Type)
+	e.SubstitutionGroupName = c.resolve(e.SubstitutionGroup)
+	switch {
+	case e.RefName.Local != "":
+		e.QName = e.RefName
+	case global || e.Form == "qualified" || (e.Form == "" && c.schema.ElementFormDefault == "qualified"):
+		e.QName = xml.Name{Space: c.schema.TargetNamespace, Local: e.Name}
+	default:
+		e.QName = xml.Name{Local: e.Name}
+	}
+	if e.ComplexType != nil {
+		c.complexType(e.ComplexType)
+	}
+	if e.SimpleType != nil {
+		c.simpleType(e.SimpleType)
+	}
+}
+
+func (c *schemaCompiler) attribute(a *xsdAttribute, global bool) {
+	a.RefName = c.resolve(a.Ref)
+	a.TypeName = c.resolve(a.Type)
+	switch {
+	case a.RefName.Local != "":
+		a.QName = a.RefName
+	case global || a.Form == "qualified" || (a.Form == "" && c.schema.AttributeFormDefault == "qualified"):
+		a.QName = xml.Name{Space: c.schema.TargetNamespace, Local: a.Name}
+	default:
+		a.QName = xml.Name{Local: a.Name}
+	}
+	if a.SimpleType != nil {
+		c.simpleType(a.SimpleType)
+	}
+}
+
+func (c *schemaCompiler) complexType(ct *xsdComplexType) {
+	c.modelGroup(ct.Sequence)
+	c.modelGroup(ct.Choice)
+	c.modelGroup(ct.All)
+	if ct.Group != nil {
+		c.group(ct.Group)
+	}
+	c.attributes(ct.Attributes, ct.AttributeGroups)
+	if d, _ := ct.derivation(); d != nil {
+		d.BaseName = c.resolve(d.Base)
+		c.modelGroup(d.Sequence)
+		c.modelGroup(d.Choice)
+		c.modelGroup(d.All)
+		if d.Group != nil {
+			c.group(d.Group)
+		}
+		c.attributes(d.Attributes, d.AttributeGroups)
+	}
+}
+
+func (c *schemaCompiler) attributes(attributes []*xsdAttribute, groups []*xsdAttributeGroup) {
+	for _, a := range attributes {
+		c.attribute(a, false)
+	}
+	for _, g := range groups {
+		c.attributeGroup(g)
+	}
+}
+
+func (c *schemaCompiler) attributeGroup(g *xsdAttributeGroup) {
+	g.RefName = c.resolve(g.Ref)
+	c.attributes(g.Attributes, g.AttributeGroups)
+}
+
+func (c *schemaCompiler) group(g *xsdGroup) {
+	g.RefName = c.resolve(g.Ref)
+	c.modelGroup(g.Sequence)
+	c.modelGroup(g.Choice)
+	c.modelGroup(g.All)
+}
+
+func (c *schemaCompiler) modelGroup(g *xsdModelGroup) {
+	if g == nil {
+		return
+	}
+	for _, p := range g.Particles {
+		switch {
+		case p.Element != nil:
+			c.element(p.Element, false)
+		case p.ModelGroup != nil:
+			c.modelGroup(p.ModelGroup)
+		case p.Group != nil:
+			c.group(p.Group)
+		}
+	}
+}
+
+func (c *schemaCompiler) simpleType(st *xsdSimpleType) {
+	switch {
+	case st.Restriction != nil:
+		st.Restriction.BaseName = c.resolve(st.Restriction.Base)
+		if st.Restriction.SimpleType != nil {
+			c.simpleType(st.Restriction.SimpleType)
+		}
+	case st.List != nil:
+		st.List.ItemTypeName = c.resolve(st.List.ItemType)
+		if st.List.SimpleType != nil {
+			c.simpleType(st.List.SimpleType)
+		}
+	case st.Union != nil:
+		st.Union.MemberTypeNames = nil
+		for _, member := range strings.Fields(st.Union.MemberTypes) {
+			st.Union.MemberTypeNames = append(st.Union.MemberTypeNames, c.resolve(member))
+		}
+		for _, member := range st.Union.SimpleTypes {
+			c.simpleType(member)
+		}
+	}
+}
+
+// isBuiltinType reports whether a type name refers to one of the XSD built-in types
+func isBuiltinType(name xml.Name) bool {
+	return name.Space == xsdNS
+}
+
+func (set *xsdSchemaSet) element(name xml.Name) *xsdElement {
+	return set.elements[name]
+}
+
+// resolveElement follows an element reference to the global element declaration
+func (set *xsdSchemaSet) resolveElement(e *xsdElement) *xsdElement {
+	if e.RefName.Local == "" {
+		return e
+	}
+	if decl, ok := set.elements[e.RefName]; ok {
+		return decl
+	}
+	return e
+}
+
+func (set *xsdSchemaSet) group(g *xsdGroup) *xsdGroup {
+	if g.RefName.Local == "" {
+		return g
+	}
+	if decl, ok := set.groups[g.RefName]; ok {
+		return decl
+	}
+	return g
+}
+
+func (set *xsdSchemaSet) attribute(a *xsdAttribute) *xsdAttribute {
+	if a.RefName.Local == "" {
+		return a
+	}
+	if decl, ok := set.attributes[a.RefName]; ok {
+		return decl
+	}
+	return a
+}
+
+// elementType returns the type of an element declaration, exactly one of the results is set.
+// Elements without a type have the built-in anyType.
+func (set *xsdSchemaSet) elementType(e *xsdElement) (*xsdComplexType, *xsdSimpleType, xml.Name) {
+	decl := set.resolveElement(e)
+	switch {
+	case decl.ComplexType != nil:
+		return decl.ComplexType, nil, xml.Name{}
+	case decl.SimpleType != nil:
+		return nil, decl.SimpleType, xml.Name{}
+	case decl.TypeName.Local == "":
+		if head, ok := set.elements[decl.SubstitutionGroupName]; ok && head != decl {
+			return set.elementType(head)
+		}
+		return nil, nil, xml.Name{Space: xsdNS, Local: "anyType"}
+	}
+	return set.namedType(decl.TypeName)
+}
+
+// namedType looks up a global type, exactly one of the results is set
+func (set *xsdSchemaSet) namedType(name xml.Name) (*xsdComplexType, *xsdSimpleType, xml.Name) {
+	if ct, ok := set.complexTypes[name]; ok {
+		return ct, nil, xml.Name{}
+	}
+	if st, ok := set.simpleTypes[name]; ok {
+		return nil, st, xml.Name{}
+	}
+	return nil, nil, name
+}
+
+// baseTypes returns the chain of complex types a type is derived from, starting with the type itself
+func (set *xsdSchemaSet) baseTypes(ct *xsdComplexType) []*xsdComplexType {
+	chain := []*xsdComplexType{ct}
+	seen := map[*xsdComplexType]bool{ct: true}
+	for {
+		d, _ := ct.derivation()
+		if d == nil {
+			return chain
+		}
+		base, ok := set.complexTypes[d.BaseName]
+		if !ok || seen[base] {
+			return chain
+		}
+		seen[base] = true
+		chain = append(chain, base)
+		ct = base
+	}
+}
+
+// contentModel returns the model groups making up the content of a complex type.
+// Extensions append their content to the content of the base type, restrictions replace it.
+func (set *xsdSchemaSet) contentModel(ct *xsdComplexType) []*xsdModelGroup {
+	var groups []*xsdModelGroup
+	for _, t := range set.baseTypes(ct) {
+		var g *xsdModelGroup
+		d, extension := t.derivation()
+		if d != nil {
+			g = d.modelGroup()
+		} else {
+			g = t.modelGroup()
+		}
+		if g != nil {
+			groups = append([]*xsdModelGroup{g}, groups...)
+		}
+		if d != nil && !extension {
+			break
+		}
+	}
+	return groups
+}
+
+// firstElement returns the first element declared by a content model, nil if it declares none
+func (set *xsdSchemaSet) firstElement(groups []*xsdModelGroup, depth int) *xsdElement {
+	if depth > 32 {
+		return nil
+	}
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		for _, p := range g.Particles {
+			var e *xsdElement
+			switch {
+			case p.Element != nil:
+				e = set.resolveElement(p.Element)
+			case p.ModelGroup != nil:
+				e = set.firstElement([]*xsdModelGroup{p.ModelGroup}, depth+1)
+			case p.Group != nil:
+				e = set.firstElement([]*xsdModelGroup{set.group(p.Group).modelGroup()}, depth+1)
+			}
+			if e != nil {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// attributeUses returns the attributes of a complex type including inherited ones and those of attribute groups.
+// References are returned as they are, since use, default and fixed of a reference belong to the use,
+// attribute resolves them to the global attribute declarations.
+func (set *xsdSchemaSet) attributeUses(ct *xsdComplexType) []*xsdAttribute {
+	var uses []*xsdAttribute
+	seen := map[xml.Name]bool{}
+	var add func(attributes []*xsdAttribute, groups []*xsdAttributeGroup, depth int)
+	add = func(attributes []*xsdAttribute, groups []*xsdAttributeGroup, depth int) {
+		for _, a := range attributes {
+			if seen[a.QName] {
+				continue
+			}
+			seen[a.QName] = true
+			uses = append(uses, a)
+		}
+		for _, g := range groups {
+			decl := g
+			if g.RefName.Local != "" {
+				decl = set.attributeGroups[g.RefName]
+			}
+			if decl != nil && depth < 32 {
+				add(decl.Attributes, decl.AttributeGroups, depth+1)
+			}
+		}
+	}
+	for _, t := range set.baseTypes(ct) {
+		add(t.Attributes, t.AttributeGroups, 0)
+		if d, _ := t.derivation(); d != nil {
+			add(d.Attributes, d.AttributeGroups, 0)
+		}
+	}
+	return uses
+}
+
+// substitutes returns the elements that can appear in place of an element, including itself.
+// Abstract elements can't appear themselves.
+func (set *xsdSchemaSet) substitutes(e *xsdElement) []*xsdElement {
+	decl := set.resolveElement(e)
+	var result []*xsdElement
+	seen := map[*xsdElement]bool{}
+	var add func(e *xsdElement)
+	add = func(e *xsdElement) {
+		if seen[e] {
+			return
+		}
+		seen[e] = true
+		if !e.Abstract {
+			result = append(result, e)
+		}
+		for _, member := range set.substitutions[e.QName] {
+			add(member)
+		}
+	}
+	add(decl)
+	return result
+}
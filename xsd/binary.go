@@ -0,0 +1,81 @@
+package xsd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Base64Binary is an xs:base64Binary, line breaks and other whitespace are ignored when decoding
+type Base64Binary []byte
+
+// HexBinary is an xs:hexBinary, it is written with upper case digits
+type HexBinary []byte
+
+func (b Base64Binary) String() string {
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func (b Base64Binary) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *Base64Binary) UnmarshalText(text []byte) error {
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(text)), ""))
+	if err != nil {
+		return fmt.Errorf("invalid base64Binary: %w", err)
+	}
+	*b = decoded
+	return nil
+}
+
+func (b Base64Binary) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalElement(e, start, b)
+}
+
+func (b *Base64Binary) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalElement(d, start, b)
+}
+
+func (b Base64Binary) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalAttr(name, b)
+}
+
+func (b *Base64Binary) UnmarshalXMLAttr(attr xml.Attr) error {
+	return unmarshalAttr(attr, b)
+}
+
+func (h HexBinary) String() string {
+	return strings.ToUpper(hex.EncodeToString(h))
+}
+
+func (h HexBinary) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *HexBinary) UnmarshalText(text []byte) error {
+	decoded, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid hexBinary: %w", err)
+	}
+	*h = decoded
+	return nil
+}
+
+func (h HexBinary) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalElement(e, start, h)
+}
+
+func (h *HexBinary) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalElement(d, start, h)
+}
+
+func (h HexBinary) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalAttr(name, h)
+}
+
+func (h *HexBinary) UnmarshalXMLAttr(attr xml.Attr) error {
+	return unmarshalAttr(attr, h)
+}
@@ -0,0 +1,116 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// Decimal is an xs:decimal with arbitrary precision. The scale, the number of fraction digits,
+// is kept so values like 1.50 are written as they were read. The zero value is 0.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+
+// ParseDecimal parses the lexical form of an xs:decimal
+func ParseDecimal(s string) (Decimal, error) {
+	if !decimalPattern.MatchString(s) {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	integer, fraction, _ := strings.Cut(strings.TrimPrefix(s, "+"), ".")
+	unscaled, ok := new(big.Int).SetString(integer+fraction, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	return Decimal{unscaled: unscaled, scale: len(fraction)}, nil
+}
+
+// NewDecimal returns the decimal unscaled * 10^-scale, e.g. NewDecimal(150, 2) is 1.50
+func NewDecimal(unscaled int64, scale int) Decimal {
+	if scale < 0 {
+		n := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-scale)), nil)
+		return Decimal{unscaled: n.Mul(n, big.NewInt(unscaled))}
+	}
+	return Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+}
+
+// Rat returns the value of the decimal
+func (d Decimal) Rat() *big.Rat {
+	r := new(big.Rat).SetInt(d.int())
+	if d.scale > 0 {
+		r.Quo(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.scale)), nil)))
+	}
+	return r
+}
+
+// Float64 returns the nearest float64 value
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// Cmp compares the values of two decimals regardless of their scale
+func (d Decimal) Cmp(other Decimal) int {
+	return d.Rat().Cmp(other.Rat())
+}
+
+// Scale returns the number of fraction digits
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+func (d Decimal) String() string {
+	unscaled := d.int()
+	digits := new(big.Int).Abs(unscaled).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(text []byte) error {
+	v, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (d Decimal) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalElement(e, start, d)
+}
+
+func (d *Decimal) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	return unmarshalElement(dec, start, d)
+}
+
+func (d Decimal) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalAttr(name, d)
+}
+
+func (d *Decimal) UnmarshalXMLAttr(attr xml.Attr) error {
+	return unmarshalAttr(attr, d)
+}
@@ -0,0 +1,34 @@
+package xsd
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecimal(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"1.50":    "1.50",
+		"+3":      "3",
+		".5":      "0.5",
+		"5.":      "5",
+		"-0.007":  "-0.007",
+		"00012.3": "12.3",
+		"123456789012345678901234567890.123456789": "123456789012345678901234567890.123456789",
+	}
+	for input, expected := range cases {
+		d, err := ParseDecimal(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, d.String(), input)
+	}
+
+	assert.Equal(t, "1.50", NewDecimal(150, 2).String())
+	assert.Equal(t, "1500", NewDecimal(15, -2).String())
+	assert.Equal(t, "0", Decimal{}.String())
+	assert.Equal(t, 0, NewDecimal(150, 2).Cmp(NewDecimal(15, 1)))
+	assert.Equal(t, big.NewRat(3, 2), NewDecimal(150, 2).Rat())
+	assert.Equal(t, 1.5, NewDecimal(15, 1).Float64())
+}
@@ -0,0 +1,150 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is an xs:duration. The components are kept as written because months and years
+// have no fixed length, use Duration to convert durations without them to a time.Duration.
+type Duration struct {
+	Negative bool
+	Years    int
+	Months   int
+	Days     int
+	Hours    int
+	Minutes  int
+	Seconds  int
+	// Nanoseconds is the fraction of the seconds
+	Nanoseconds int
+}
+
+var durationPattern = regexp.MustCompile(`^(-)?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)(?:\.(\d+))?S)?)?$`)
+
+// ParseDuration parses the lexical form of an xs:duration, fractions of seconds are limited to nanoseconds
+func ParseDuration(s string) (Duration, error) {
+	m := durationPattern.FindStringSubmatch(s)
+	if m == nil || strings.HasSuffix(s, "P") || strings.HasSuffix(s, "T") {
+		return Duration{}, fmt.Errorf("invalid duration %q", s)
+	}
+	d := Duration{Negative: m[1] != ""}
+	fields := []*int{&d.Years, &d.Months, &d.Days, &d.Hours, &d.Minutes, &d.Seconds}
+	for i, field := range fields {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return Duration{}, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		*field = n
+	}
+	if fraction := m[8]; fraction != "" {
+		if len(fraction) > 9 {
+			return Duration{}, fmt.Errorf("invalid duration %q: fractions of seconds are limited to nanoseconds", s)
+		}
+		d.Nanoseconds, _ = strconv.Atoi(fraction + strings.Repeat("0", 9-len(fraction)))
+	}
+	return d, nil
+}
+
+// FromDuration converts a time.Duration to hours, minutes and seconds
+func FromDuration(td time.Duration) Duration {
+	var d Duration
+	if td < 0 {
+		d.Negative = true
+		td = -td
+	}
+	d.Hours = int(td / time.Hour)
+	d.Minutes = int(td % time.Hour / time.Minute)
+	d.Seconds = int(td % time.Minute / time.Second)
+	d.Nanoseconds = int(td % time.Second)
+	return d
+}
+
+// Duration converts the duration to a time.Duration, days count as 24 hours.
+// Durations with years or months can't be converted.
+func (d Duration) Duration() (time.Duration, error) {
+	if d.Years != 0 || d.Months != 0 {
+		return 0, errors.New("durations with years or months have no fixed length")
+	}
+	td := time.Duration(d.Days)*24*time.Hour + time.Duration(d.Hours)*time.Hour + time.Duration(d.Minutes)*time.Minute +
+		time.Duration(d.Seconds)*time.Second + time.Duration(d.Nanoseconds)
+	if d.Negative {
+		td = -td
+	}
+	return td, nil
+}
+
+func (d Duration) String() string {
+	var b strings.Builder
+	if d.Negative {
+		b.WriteByte('-')
+	}
+	b.WriteByte('P')
+	for _, c := range []struct {
+		n    int
+		unit byte
+	}{{d.Years, 'Y'}, {d.Months, 'M'}, {d.Days, 'D'}} {
+		if c.n != 0 {
+			b.WriteString(strconv.Itoa(c.n))
+			b.WriteByte(c.unit)
+		}
+	}
+	if d.Hours == 0 && d.Minutes == 0 && d.Seconds == 0 && d.Nanoseconds == 0 {
+		if b.Len() <= 2 {
+			// the zero duration needs at least one component
+			b.WriteString("T0S")
+		}
+		return b.String()
+	}
+	b.WriteByte('T')
+	if d.Hours != 0 {
+		b.WriteString(strconv.Itoa(d.Hours) + "H")
+	}
+	if d.Minutes != 0 {
+		b.WriteString(strconv.Itoa(d.Minutes) + "M")
+	}
+	if d.Seconds != 0 || d.Nanoseconds != 0 {
+		b.WriteString(strconv.Itoa(d.Seconds))
+		if d.Nanoseconds != 0 {
+			b.WriteString(strings.TrimRight(fmt.Sprintf(".%09d", d.Nanoseconds), "0"))
+		}
+		b.WriteByte('S')
+	}
+	return b.String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (d Duration) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalElement(e, start, d)
+}
+
+func (d *Duration) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	return unmarshalElement(dec, start, d)
+}
+
+func (d Duration) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalAttr(name, d)
+}
+
+func (d *Duration) UnmarshalXMLAttr(attr xml.Attr) error {
+	return unmarshalAttr(attr, d)
+}
@@ -0,0 +1,25 @@
+package xsd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuration(t *testing.T) {
+	t.Parallel()
+	d, err := ParseDuration("P1DT2H30M0.25S")
+	require.NoError(t, err)
+	td, err := d.Duration()
+	require.NoError(t, err)
+	assert.Equal(t, 26*time.Hour+30*time.Minute+250*time.Millisecond, td)
+
+	assert.Equal(t, "-PT1H1.5S", FromDuration(-(time.Hour + 1500*time.Millisecond)).String())
+	assert.Equal(t, "PT0S", Duration{}.String())
+	assert.Equal(t, "P1M", Duration{Months: 1}.String())
+
+	_, err = Duration{Months: 1}.Duration()
+	assert.EqualError(t, err, "durations with years or months have no fixed length")
+}
@@ -0,0 +1,184 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateTime is an xs:dateTime. Values without timezone have NoTimezone set and their Time in UTC,
+// they are written without timezone again.
+type DateTime struct {
+	Time       time.Time
+	NoTimezone bool
+}
+
+// Date is an xs:date, Time holds midnight of the day. Values without timezone have NoTimezone set.
+type Date struct {
+	Time       time.Time
+	NoTimezone bool
+}
+
+// Time is an xs:time, Time holds the time of day on January 1st of year 0. Values without timezone have NoTimezone set.
+type Time struct {
+	Time       time.Time
+	NoTimezone bool
+}
+
+const (
+	dateTimeLayout = "2006-01-02T15:04:05.999999999"
+	dateLayout     = "2006-01-02"
+	timeLayout     = "15:04:05.999999999"
+	zoneLayout     = "Z07:00"
+)
+
+// ParseDateTime parses the lexical form of an xs:dateTime, the end of day 24:00:00 is the start of the next day
+func ParseDateTime(s string) (DateTime, error) {
+	t, noTimezone, err := parseTime("dateTime", dateTimeLayout, s)
+	return DateTime{Time: t, NoTimezone: noTimezone}, err
+}
+
+// ParseDate parses the lexical form of an xs:date
+func ParseDate(s string) (Date, error) {
+	t, noTimezone, err := parseTime("date", dateLayout, s)
+	return Date{Time: t, NoTimezone: noTimezone}, err
+}
+
+// ParseTime parses the lexical form of an xs:time, 24:00:00 is midnight
+func ParseTime(s string) (Time, error) {
+	t, noTimezone, err := parseTime("time", timeLayout, s)
+	return Time{Time: t, NoTimezone: noTimezone}, err
+}
+
+func parseTime(typ, layout, s string) (time.Time, bool, error) {
+	hasZone := hasTimezone(s)
+	if hasZone {
+		layout += zoneLayout
+	}
+	// 24:00:00 is allowed for the end of a day, time.Parse only accepts hours up to 23
+	value, endOfDay := s, false
+	if i := strings.Index(s, "24:00:00"); i >= 0 && (i == 0 || s[i-1] == 'T') {
+		value, endOfDay = s[:i]+"00:00:00"+s[i+len("24:00:00"):], true
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid %s %q", typ, s)
+	}
+	if endOfDay && typ == "dateTime" {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, !hasZone, nil
+}
+
+// hasTimezone reports whether a value ends with a timezone, Z or ±hh:mm
+func hasTimezone(s string) bool {
+	n := len(s)
+	return strings.HasSuffix(s, "Z") || (n >= 6 && (s[n-6] == '+' || s[n-6] == '-') && s[n-3] == ':')
+}
+
+func formatTime(t time.Time, layout string, noTimezone bool) string {
+	if noTimezone {
+		return t.Format(layout)
+	}
+	return t.Format(layout + zoneLayout)
+}
+
+func (t DateTime) String() string {
+	return formatTime(t.Time, dateTimeLayout, t.NoTimezone)
+}
+
+func (t DateTime) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *DateTime) UnmarshalText(text []byte) error {
+	v, err := ParseDateTime(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+func (t DateTime) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalElement(e, start, t)
+}
+
+func (t *DateTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalElement(d, start, t)
+}
+
+func (t DateTime) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalAttr(name, t)
+}
+
+func (t *DateTime) UnmarshalXMLAttr(attr xml.Attr) error {
+	return unmarshalAttr(attr, t)
+}
+
+func (d Date) String() string {
+	return formatTime(d.Time, dateLayout, d.NoTimezone)
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalText(text []byte) error {
+	v, err := ParseDate(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (d Date) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalElement(e, start, d)
+}
+
+func (d *Date) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	return unmarshalElement(dec, start, d)
+}
+
+func (d Date) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalAttr(name, d)
+}
+
+func (d *Date) UnmarshalXMLAttr(attr xml.Attr) error {
+	return unmarshalAttr(attr, d)
+}
+
+func (t Time) String() string {
+	return formatTime(t.Time, timeLayout, t.NoTimezone)
+}
+
+func (t Time) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *Time) UnmarshalText(text []byte) error {
+	v, err := ParseTime(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+func (t Time) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalElement(e, start, t)
+}
+
+func (t *Time) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalElement(d, start, t)
+}
+
+func (t Time) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return marshalAttr(name, t)
+}
+
+func (t *Time) UnmarshalXMLAttr(attr xml.Attr) error {
+	return unmarshalAttr(attr, t)
+}
@@ -0,0 +1,59 @@
+package xsd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimes(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		parse    func(string) (any, error)
+		input    string
+		expected time.Time
+		output   string
+	}{
+		{
+			parse:    func(s string) (any, error) { return ParseDateTime(s) },
+			input:    "2024-03-01T10:30:00Z",
+			expected: time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+			output:   "2024-03-01T10:30:00Z",
+		},
+		{
+			parse:    func(s string) (any, error) { return ParseDateTime(s) },
+			input:    "2024-02-29T24:00:00",
+			expected: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			output:   "2024-03-01T00:00:00",
+		},
+		{
+			parse:    func(s string) (any, error) { return ParseDate(s) },
+			input:    "2024-03-01-05:00",
+			expected: time.Date(2024, 3, 1, 0, 0, 0, 0, time.FixedZone("", -5*3600)),
+			output:   "2024-03-01-05:00",
+		},
+		{
+			parse:    func(s string) (any, error) { return ParseTime(s) },
+			input:    "24:00:00",
+			expected: time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC),
+			output:   "00:00:00",
+		},
+	}
+	for _, tc := range cases {
+		v, err := tc.parse(tc.input)
+		require.NoError(t, err, tc.input)
+		var got time.Time
+		switch v := v.(type) {
+		case DateTime:
+			got = v.Time
+		case Date:
+			got = v.Time
+		case Time:
+			got = v.Time
+		}
+		assert.True(t, tc.expected.Equal(got), "%s: expected %v, got %v", tc.input, tc.expected, got)
+		assert.Equal(t, tc.output, v.(interface{ String() string }).String())
+	}
+}
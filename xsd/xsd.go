@@ -0,0 +1,69 @@
+// Package xsd provides Go types for XSD built-in types that encoding/xml can't represent faithfully.
+//
+// The types implement xml.Marshaler, xml.Unmarshaler, xml.MarshalerAttr and xml.UnmarshalerAttr,
+// values are written back in the lexical form they were read in where it carries information,
+// like the missing timezone of a dateTime or the scale of a decimal.
+// see https://www.w3.org/TR/xmlschema-2/#built-in-datatypes
+package xsd
+
+import (
+	"encoding"
+	"encoding/xml"
+	"strings"
+)
+
+// Namespace is the namespace of the XSD built-in types
+const Namespace = "http://www.w3.org/2001/XMLSchema"
+
+// textValue is implemented by the pointers to the types of the package
+type textValue interface {
+	encoding.TextMarshaler
+	encoding.TextUnmarshaler
+	xml.Marshaler
+	xml.Unmarshaler
+	xml.MarshalerAttr
+	xml.UnmarshalerAttr
+}
+
+var (
+	_ textValue = (*DateTime)(nil)
+	_ textValue = (*Date)(nil)
+	_ textValue = (*Time)(nil)
+	_ textValue = (*Duration)(nil)
+	_ textValue = (*Decimal)(nil)
+	_ textValue = (*Base64Binary)(nil)
+	_ textValue = (*HexBinary)(nil)
+)
+
+func marshalElement(e *xml.Encoder, start xml.StartElement, v encoding.TextMarshaler) error {
+	text, err := v.MarshalText()
+	if err != nil {
+		return err
+	}
+	return e.EncodeElement(string(text), start)
+}
+
+func unmarshalElement(d *xml.Decoder, start xml.StartElement, v encoding.TextUnmarshaler) error {
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return err
+	}
+	return v.UnmarshalText([]byte(collapse(text)))
+}
+
+func marshalAttr(name xml.Name, v encoding.TextMarshaler) (xml.Attr, error) {
+	text, err := v.MarshalText()
+	if err != nil {
+		return xml.Attr{}, err
+	}
+	return xml.Attr{Name: name, Value: string(text)}, nil
+}
+
+func unmarshalAttr(attr xml.Attr, v encoding.TextUnmarshaler) error {
+	return v.UnmarshalText([]byte(collapse(attr.Value)))
+}
+
+// collapse applies the whitespace collapsing of the non-string built-in types
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
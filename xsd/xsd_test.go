@@ -0,0 +1,66 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type values struct {
+	XMLName  xml.Name     `xml:"values"`
+	At       DateTime     `xml:"at,attr"`
+	DateTime DateTime     `xml:"dateTime"`
+	Date     Date         `xml:"date"`
+	Time     Time         `xml:"time"`
+	Duration Duration     `xml:"duration"`
+	Decimal  Decimal      `xml:"decimal"`
+	Base64   Base64Binary `xml:"base64"`
+	Hex      HexBinary    `xml:"hex"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+	doc := `<values at="2024-03-01T10:30:00.5+01:00">` +
+		`<dateTime>2024-03-01T10:30:00</dateTime>` +
+		`<date>2024-03-01Z</date>` +
+		`<time>23:59:59.123-05:00</time>` +
+		`<duration>-P1Y2M3DT4H5M6.7S</duration>` +
+		`<decimal>-0.050</decimal>` +
+		`<base64>aGVsbG8=</base64>` +
+		`<hex>0AFF</hex>` +
+		`</values>`
+
+	var v values
+	require.NoError(t, xml.Unmarshal([]byte(doc), &v))
+	assert.False(t, v.At.NoTimezone)
+	assert.True(t, v.DateTime.NoTimezone)
+	assert.Equal(t, 30, v.DateTime.Time.Minute())
+	assert.Equal(t, Duration{Negative: true, Years: 1, Months: 2, Days: 3, Hours: 4, Minutes: 5, Seconds: 6, Nanoseconds: 700000000}, v.Duration)
+	assert.Equal(t, 3, v.Decimal.Scale())
+	assert.Equal(t, []byte("hello"), []byte(v.Base64))
+	assert.Equal(t, []byte{0x0a, 0xff}, []byte(v.Hex))
+
+	out, err := xml.Marshal(v)
+	require.NoError(t, err)
+	assert.Equal(t, doc, string(out))
+}
+
+func TestInvalidValues(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		doc string
+		v   any
+		err string
+	}{
+		{`<v>2024-13-01T00:00:00</v>`, &DateTime{}, `invalid dateTime "2024-13-01T00:00:00"`},
+		{`<v>P</v>`, &Duration{}, `invalid duration "P"`},
+		{`<v>PT1.0000000001S</v>`, &Duration{}, `invalid duration "PT1.0000000001S": fractions of seconds are limited to nanoseconds`},
+		{`<v>1e3</v>`, &Decimal{}, `invalid decimal "1e3"`},
+		{`<v>ABC</v>`, &HexBinary{}, `invalid hexBinary: encoding/hex: odd length hex string`},
+	}
+	for _, tc := range cases {
+		assert.EqualError(t, xml.Unmarshal([]byte(tc.doc), tc.v), tc.err)
+	}
+}
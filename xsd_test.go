@@ -0,0 +1,216 @@
+package gosoap
+
+import (
+	"context"
+	"encoding/xml"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const schemaTestWSDL = `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/" xmlns:tns="urn:shop" xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:shop">
+  <wsdl:types>
+    <xs:schema targetNamespace="urn:shop" elementFormDefault="qualified">
+      <xs:attributeGroup name="Audit">
+        <xs:attribute name="createdBy" type="xs:string" use="required"/>
+      </xs:attributeGroup>
+      <xs:group name="Contact">
+        <xs:choice>
+          <xs:element name="Email" type="xs:string"/>
+          <xs:element name="Phone" type="xs:string"/>
+        </xs:choice>
+      </xs:group>
+      <xs:complexType name="Party">
+        <xs:sequence>
+          <xs:element name="Name" type="xs:string"/>
+          <xs:group ref="tns:Contact"/>
+        </xs:sequence>
+        <xs:attributeGroup ref="tns:Audit"/>
+      </xs:complexType>
+      <xs:complexType name="Customer">
+        <xs:complexContent>
+          <xs:extension base="tns:Party">
+            <xs:sequence>
+              <xs:element ref="tns:Payment" minOccurs="0" maxOccurs="unbounded"/>
+            </xs:sequence>
+            <xs:attribute name="vip" type="xs:boolean"/>
+          </xs:extension>
+        </xs:complexContent>
+      </xs:complexType>
+      <xs:complexType name="Price">
+        <xs:simpleContent>
+          <xs:extension base="tns:Amount">
+            <xs:attribute name="currency" type="xs:string"/>
+          </xs:extension>
+        </xs:simpleContent>
+      </xs:complexType>
+      <xs:simpleType name="Amount">
+        <xs:restriction base="xs:decimal">
+          <xs:minInclusive value="0"/>
+          <xs:fractionDigits value="2"/>
+        </xs:restriction>
+      </xs:simpleType>
+      <xs:simpleType name="Tags">
+        <xs:list itemType="xs:token"/>
+      </xs:simpleType>
+      <xs:simpleType name="Size">
+        <xs:union memberTypes="xs:int tns:Label"/>
+      </xs:simpleType>
+      <xs:simpleType name="Label">
+        <xs:restriction base="xs:string">
+          <xs:enumeration value="S"/>
+          <xs:enumeration value="M"/>
+          <xs:enumeration value="L"/>
+        </xs:restriction>
+      </xs:simpleType>
+      <xs:element name="Payment" type="xs:anyType" abstract="true"/>
+      <xs:element name="Card" type="xs:string" substitutionGroup="tns:Payment"/>
+      <xs:element name="Cash" type="tns:Price" substitutionGroup="tns:Payment"/>
+      <xs:element name="Customer" type="tns:Customer"/>
+      <xs:element name="Note">
+        <xs:complexType>
+          <xs:all>
+            <xs:element name="Text" type="xs:string" form="unqualified"/>
+            <xs:any namespace="##other" processContents="lax" minOccurs="0"/>
+          </xs:all>
+        </xs:complexType>
+      </xs:element>
+    </xs:schema>
+  </wsdl:types>
+</wsdl:definitions>`
+
+func shopName(local string) xml.Name {
+	return xml.Name{Space: "urn:shop", Local: local}
+}
+
+func elementNames(groups []*xsdModelGroup) []xml.Name {
+	var names []xml.Name
+	var walk func(g *xsdModelGroup)
+	walk = func(g *xsdModelGroup) {
+		for _, p := range g.Particles {
+			switch {
+			case p.Element != nil:
+				names = append(names, p.Element.QName)
+			case p.ModelGroup != nil:
+				walk(p.ModelGroup)
+			case p.Group != nil:
+				names = append(names, xml.Name{Local: "group:" + p.Group.RefName.Local})
+			}
+		}
+	}
+	for _, g := range groups {
+		walk(g)
+	}
+	return names
+}
+
+func TestSchemaSet(t *testing.T) {
+	t.Parallel()
+	d, err := getWSDLDefinitions(context.Background(), SourceFromBytes([]byte(schemaTestWSDL)), &Config{})
+	require.NoError(t, err)
+	set := d.schemas
+
+	t.Run("extension", func(t *testing.T) {
+		customer := set.element(shopName("Customer"))
+		require.NotNil(t, customer)
+		ct, st, builtin := set.elementType(customer)
+		require.NotNil(t, ct)
+		assert.Nil(t, st)
+		assert.Empty(t, builtin)
+		assert.Equal(t, shopName("Customer"), ct.QName)
+		assert.Equal(t, []xml.Name{
+			shopName("Name"),
+			{Local: "group:Contact"},
+			shopName("Payment"),
+		}, elementNames(set.contentModel(ct)))
+
+		var attributes []xml.Name
+		for _, a := range set.attributeUses(ct) {
+			attributes = append(attributes, a.QName)
+		}
+		assert.Equal(t, []xml.Name{{Local: "vip"}, {Local: "createdBy"}}, attributes)
+	})
+
+	t.Run("group reference", func(t *testing.T) {
+		party := set.complexTypes[shopName("Party")]
+		require.NotNil(t, party)
+		ref := party.Sequence.Particles[1].Group
+		group := set.group(ref)
+		require.NotNil(t, group.Choice)
+		assert.Equal(t, "choice", group.Choice.Kind)
+		assert.Equal(t, []xml.Name{shopName("Email"), shopName("Phone")}, elementNames([]*xsdModelGroup{group.Choice}))
+	})
+
+	t.Run("element reference and substitution group", func(t *testing.T) {
+		customer := set.complexTypes[shopName("Customer")]
+		d, _ := customer.derivation()
+		ref := d.Sequence.Particles[0].Element
+		assert.Equal(t, 0, occurs(ref.MinOccurs))
+		assert.Equal(t, -1, occurs(ref.MaxOccurs))
+		payment := set.resolveElement(ref)
+		assert.True(t, payment.Abstract)
+
+		var names []xml.Name
+		for _, e := range set.substitutes(ref) {
+			names = append(names, e.QName)
+		}
+		assert.Equal(t, []xml.Name{shopName("Card"), shopName("Cash")}, names)
+	})
+
+	t.Run("simple content", func(t *testing.T) {
+		price := set.complexTypes[shopName("Price")]
+		d, extension := price.derivation()
+		assert.True(t, extension)
+		_, amount, _ := set.namedType(d.BaseName)
+		require.NotNil(t, amount)
+		assert.Equal(t, xml.Name{Space: xsdNS, Local: "decimal"}, amount.Restriction.BaseName)
+		minInclusive, _ := amount.Restriction.Facets.get("minInclusive")
+		assert.Equal(t, "0", minInclusive)
+		fractionDigits, _ := amount.Restriction.Facets.get("fractionDigits")
+		assert.Equal(t, "2", fractionDigits)
+	})
+
+	t.Run("list and union", func(t *testing.T) {
+		tags := set.simpleTypes[shopName("Tags")]
+		assert.Equal(t, xml.Name{Space: xsdNS, Local: "token"}, tags.List.ItemTypeName)
+		size := set.simpleTypes[shopName("Size")]
+		assert.Equal(t, []xml.Name{{Space: xsdNS, Local: "int"}, shopName("Label")}, size.Union.MemberTypeNames)
+		label := set.simpleTypes[shopName("Label")]
+		assert.Equal(t, []string{"S", "M", "L"}, label.Restriction.Facets.values("enumeration"))
+	})
+
+	t.Run("all and any", func(t *testing.T) {
+		ct, _, _ := set.elementType(set.element(shopName("Note")))
+		require.NotNil(t, ct)
+		group := ct.modelGroup()
+		assert.Equal(t, "all", group.Kind)
+		require.Len(t, group.Particles, 2)
+		assert.Equal(t, xml.Name{Local: "Text"}, group.Particles[0].Element.QName)
+		assert.Equal(t, "##other", group.Particles[1].Any.Namespace)
+	})
+}
+
+func TestSchemaSetAcrossDocuments(t *testing.T) {
+	t.Parallel()
+	dir, err := filepath.Abs("testdata/split")
+	require.NoError(t, err)
+	d, err := getWSDLDefinitions(context.Background(), SourceFromURI("file://"+filepath.Join(dir, "main.wsdl")), &Config{})
+	require.NoError(t, err)
+
+	getQuote := d.schemas.element(xml.Name{Space: "http://example.com/split", Local: "GetQuote"})
+	require.NotNil(t, getQuote)
+	symbol := getQuote.ComplexType.Sequence.Particles[0].Element
+	_, st, _ := d.schemas.elementType(symbol)
+	require.NotNil(t, st, "type from imported schema")
+	assert.Equal(t, []string{"[A-Z]+"}, st.Restriction.Facets.values("pattern"))
+
+	// chameleon include takes the namespace of the including schema
+	response := d.schemas.element(xml.Name{Space: "http://example.com/split", Local: "GetQuoteResponse"})
+	require.NotNil(t, response)
+	price := response.ComplexType.Sequence.Particles[0].Element
+	assert.Equal(t, xml.Name{Space: "http://example.com/split", Local: "Price"}, price.QName)
+	assert.Equal(t, xml.Name{Space: xsdNS, Local: "decimal"}, price.TypeName)
+}